@@ -0,0 +1,279 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flokiorg/go-flokicoin/chainutil"
+)
+
+var (
+	// addressBookFile holds the address book within the wallet directory,
+	// shared by its wallets.
+	addressBookFile = "addressbook.json"
+
+	payeeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+)
+
+// Payee is a named destination of the address book.
+type Payee struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Network string `json:"network"`
+	Note    string `json:"note,omitempty"`
+}
+
+func (wch *WalletCliHandler) addressBookPath() string {
+	return filepath.Join(wch.cfg.WalletDir, addressBookFile)
+}
+
+// loadAddressBook returns the payees of the address book, none when it does
+// not exist yet.
+func (wch *WalletCliHandler) loadAddressBook() []Payee {
+	data, err := os.ReadFile(wch.addressBookPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		log.Fatalf("unable to read address book: %v", err)
+	}
+
+	var payees []Payee
+	if err := json.Unmarshal(data, &payees); err != nil {
+		log.Fatalf("unable to read address book %s: %v", wch.addressBookPath(), err)
+	}
+	return payees
+}
+
+func (wch *WalletCliHandler) saveAddressBook(payees []Payee) {
+	sort.Slice(payees, func(i, j int) bool { return payees[i].Name < payees[j].Name })
+	data, err := json.MarshalIndent(payees, "", "  ")
+	if err != nil {
+		log.Fatalf("unable to encode address book: %v", err)
+	}
+	if err := os.MkdirAll(wch.cfg.WalletDir, 0700); err != nil {
+		log.Fatalf("unable to save address book: %v", err)
+	}
+	if err := os.WriteFile(wch.addressBookPath(), append(data, '\n'), 0600); err != nil {
+		log.Fatalf("unable to save address book: %v", err)
+	}
+}
+
+// newPayee checks the name and the address of a payee, the address must be
+// for the network in use.
+func (wch *WalletCliHandler) newPayee(name, strAddress, note string) (Payee, error) {
+	if !payeeNamePattern.MatchString(name) {
+		return Payee{}, fmt.Errorf("invalid name %q, use letters, digits, '.', '_', '@' and '-'", name)
+	}
+	addr, err := chainutil.DecodeAddress(strAddress, wch.network)
+	if err != nil || !addr.IsForNet(wch.network) {
+		return Payee{}, fmt.Errorf("%s is not a %s address", strAddress, wch.network.Name)
+	}
+	return Payee{Name: name, Address: addr.EncodeAddress(), Network: wch.network.Name, Note: note}, nil
+}
+
+// AddPayee adds a named destination to the address book, replacing the one of
+// the same name when replace is set.
+func (wch *WalletCliHandler) AddPayee(name, strAddress, note string, replace bool) {
+	payee, err := wch.newPayee(name, strAddress, note)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	payees := wch.loadAddressBook()
+	for i := range payees {
+		if payees[i].Name != name {
+			continue
+		}
+		if !replace {
+			log.Fatalf("%q is already in the address book, use --replace to change it", name)
+		}
+		payees[i] = payee
+		wch.saveAddressBook(payees)
+		fmt.Printf("Updated %s: %s\n", name, payee.Address)
+		return
+	}
+
+	wch.saveAddressBook(append(payees, payee))
+	fmt.Printf("Added %s: %s\n", name, payee.Address)
+}
+
+// RemovePayee removes a destination from the address book.
+func (wch *WalletCliHandler) RemovePayee(name string) {
+	payees := wch.loadAddressBook()
+	for i := range payees {
+		if payees[i].Name == name {
+			wch.saveAddressBook(append(payees[:i], payees[i+1:]...))
+			fmt.Printf("Removed %s\n", name)
+			return
+		}
+	}
+	log.Fatalf("%q is not in the address book", name)
+}
+
+// ListPayees prints the address book, the destinations of other networks
+// included.
+func (wch *WalletCliHandler) ListPayees() {
+	payees := wch.loadAddressBook()
+	if len(payees) == 0 {
+		fmt.Println("The address book is empty.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, payee := range payees {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", payee.Name, payee.Address, payee.Network, payee.Note)
+	}
+	w.Flush()
+}
+
+// ExportPayees writes the address book to path as JSON, or CSV after its
+// extension, "-" for standard output.
+func (wch *WalletCliHandler) ExportPayees(path string) {
+	payees := wch.loadAddressBook()
+
+	out := os.Stdout
+	if path != "-" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatalf("unable to export address book: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		w := csv.NewWriter(out)
+		w.Write([]string{"name", "address", "network", "note"})
+		for _, payee := range payees {
+			w.Write([]string{payee.Name, payee.Address, payee.Network, payee.Note})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatalf("unable to export address book: %v", err)
+		}
+	} else {
+		data, err := json.MarshalIndent(payees, "", "  ")
+		if err != nil {
+			log.Fatalf("unable to encode address book: %v", err)
+		}
+		if _, err := out.Write(append(data, '\n')); err != nil {
+			log.Fatalf("unable to export address book: %v", err)
+		}
+	}
+	if path != "-" {
+		fmt.Printf("%d payees exported to %s\n", len(payees), path)
+	}
+}
+
+// ImportPayees adds the payees of a JSON file, or CSV after its extension,
+// "-" for standard input. Every address is checked against the network in use
+// before anything is saved, payees of the same name are replaced when replace
+// is set.
+func (wch *WalletCliHandler) ImportPayees(path string, replace bool) {
+	in := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("unable to import address book: %v", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	var imported []Payee
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		imported = readPayeesCSV(in)
+	} else if err := json.NewDecoder(in).Decode(&imported); err != nil {
+		log.Fatalf("unable to read %s: %v", path, err)
+	}
+
+	payees := wch.loadAddressBook()
+	index := make(map[string]int, len(payees))
+	for i, payee := range payees {
+		index[payee.Name] = i
+	}
+
+	var added, updated int
+	for _, entry := range imported {
+		payee, err := wch.newPayee(entry.Name, entry.Address, entry.Note)
+		if err != nil {
+			log.Fatalf("unable to import %q: %v", entry.Name, err)
+		}
+		i, exists := index[payee.Name]
+		switch {
+		case !exists:
+			index[payee.Name] = len(payees)
+			payees = append(payees, payee)
+			added++
+		case !replace && payees[i].Address != payee.Address:
+			log.Fatalf("%q is already in the address book with another address, use --replace to change it", payee.Name)
+		default:
+			payees[i] = payee
+			updated++
+		}
+	}
+
+	wch.saveAddressBook(payees)
+	fmt.Printf("%d payees added, %d updated\n", added, updated)
+}
+
+// readPayeesCSV reads the columns written by ExportPayees, or a name, an
+// address and an optional note. A first row starting with "name" is taken as
+// the header.
+func readPayeesCSV(in io.Reader) []Payee {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		log.Fatalf("unable to read address book: %v", err)
+	}
+
+	var payees []Payee
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+		if len(record) < 2 {
+			log.Fatalf("line %d: expected a name and an address", i+1)
+		}
+		payee := Payee{Name: strings.TrimSpace(record[0]), Address: strings.TrimSpace(record[1])}
+		if len(record) > 3 {
+			payee.Note = record[3]
+		} else if len(record) == 3 {
+			payee.Note = record[2]
+		}
+		payees = append(payees, payee)
+	}
+	return payees
+}
+
+// PayeeAddress returns the address of a payee of the address book, it must be
+// for the network in use.
+func (wch *WalletCliHandler) PayeeAddress(name string) string {
+	for _, payee := range wch.loadAddressBook() {
+		if payee.Name != name {
+			continue
+		}
+		if payee.Network != wch.network.Name {
+			log.Fatalf("payee %q is a %s address, not %s", name, payee.Network, wch.network.Name)
+		}
+		return payee.Address
+	}
+	log.Fatalf("%q is not in the address book", name)
+	return ""
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	. "github.com/flokiorg/fcli/utils"
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// knownNetworks are the networks an address of another network is looked up
+// in, to tell which one it belongs to.
+var knownNetworks = []*chaincfg.Params{
+	&chaincfg.MainNetParams,
+	&chaincfg.TestNet3Params,
+	&chaincfg.RegressionNetParams,
+}
+
+// AddressInfo prints what is known about an address: its validity, type and
+// script, and for wallet addresses their account, path, usage, label and
+// balance.
+func (wch *WalletCliHandler) AddressInfo(strAddress string) {
+	fmt.Printf("Address:  %s\n", strAddress)
+
+	addr, err := chainutil.DecodeAddress(strAddress, wch.network)
+	if err != nil || !addr.IsForNet(wch.network) {
+		fmt.Printf("Valid:    false (not a %s address)\n", wch.network.Name)
+		for _, network := range knownNetworks {
+			if network == wch.network {
+				continue
+			}
+			if other, err := chainutil.DecodeAddress(strAddress, network); err == nil && other.IsForNet(network) {
+				fmt.Printf("Network:  %s\n", network.Name)
+				break
+			}
+		}
+		return
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		log.Fatalf("unable to build script: %v", err)
+	}
+	disasm, _ := txscript.DisasmString(pkScript)
+
+	labels, err := wch.AddressLabels()
+	if err != nil {
+		log.Fatalf("unable to fetch labels: %v", err)
+	}
+
+	details, err := wch.AddressDetails(addr)
+	switch {
+	case waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound):
+		details = nil
+	case err != nil:
+		log.Fatalf("unable to look up address: %v", err)
+	}
+
+	addrType := addressType(addr)
+	if details != nil {
+		addrType = details.Type
+	}
+
+	fmt.Printf("Valid:    true (%s)\n", wch.network.Name)
+	fmt.Printf("Type:     %s\n", StrAddrType(addrType))
+	fmt.Printf("Script:   %s\n", hex.EncodeToString(pkScript))
+	fmt.Printf("          %s\n", disasm)
+
+	if details != nil {
+		fmt.Printf("Owner:    this wallet\n")
+		fmt.Printf("Account:  %d %q (%s)\n", details.Account, details.AccountName, details.Scope)
+		switch {
+		case details.Imported:
+			fmt.Printf("Key:      imported\n")
+		case details.Path != "":
+			branch := "receive"
+			if details.Branch == waddrmgr.InternalBranch {
+				branch = "change"
+			}
+			fmt.Printf("Path:     %s (%s, index %d)\n", details.Path, branch, details.Index)
+		}
+		fmt.Printf("Used:     %t\n", details.Used)
+		fmt.Printf("Label:    %s\n", labels[addr.EncodeAddress()])
+		fmt.Printf("Balance:  %f\n", details.Balance.ToFLC())
+		return
+	}
+
+	// Addresses tracked without their key are kept apart from the wallet
+	// ones, with their balance as of the last sync.
+	watched, err := wch.WatchedAddresses()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only addresses: %v", err)
+	}
+	for _, wad := range watched {
+		if wad.Address == addr.EncodeAddress() {
+			fmt.Printf("Owner:    watch-only\n")
+			fmt.Printf("Used:     %t\n", len(wad.History) > 0)
+			fmt.Printf("Label:    %s\n", labels[wad.Address])
+			fmt.Printf("Balance:  %f (last sync)\n", wad.Balance.ToFLC())
+			return
+		}
+	}
+
+	accounts, err := wch.MultisigAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch multisig accounts: %v", err)
+	}
+	for _, account := range accounts {
+		for _, ma := range account.Addresses {
+			if ma.Address != addr.EncodeAddress() {
+				continue
+			}
+			branch := "receive"
+			if ma.Branch == waddrmgr.InternalBranch {
+				branch = "change"
+			}
+			fmt.Printf("Owner:    multisig %s (%d-of-%d)\n", account.Name, account.Threshold, len(account.Keys))
+			fmt.Printf("Path:     %s, index %d\n", branch, ma.Index)
+			fmt.Printf("Used:     %t\n", len(ma.History) > 0)
+			fmt.Printf("Label:    %s\n", labels[ma.Address])
+			fmt.Printf("Balance:  %f (last sync)\n", ma.Balance.ToFLC())
+			return
+		}
+	}
+
+	fmt.Printf("Owner:    not this wallet\n")
+}
+
+// addressType returns the type of an address the wallet does not know, nested
+// SegWit addresses can not be told apart from other scripts.
+func addressType(addr chainutil.Address) waddrmgr.AddressType {
+	switch addr.(type) {
+	case *chainutil.AddressScriptHash:
+		return waddrmgr.Script
+	case *chainutil.AddressPubKey:
+		return waddrmgr.RawPubKey
+	case *chainutil.AddressWitnessPubKeyHash:
+		return waddrmgr.WitnessPubKey
+	case *chainutil.AddressWitnessScriptHash:
+		return waddrmgr.WitnessScript
+	case *chainutil.AddressTaproot:
+		return waddrmgr.TaprootPubKey
+	default:
+		return waddrmgr.PubKeyHash
+	}
+}
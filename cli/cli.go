@@ -5,22 +5,130 @@
 package cli
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/flokiorg/fcli/slip39"
 	. "github.com/flokiorg/fcli/utils"
+	walletmgr "github.com/flokiorg/fcli/wallet"
 	"github.com/flokiorg/go-flokicoin/chaincfg"
-	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
 	"github.com/flokiorg/walletd/waddrmgr"
-	"github.com/flokiorg/walletd/walletmgr"
-	"github.com/flokiorg/walletd/walletseed/bip39"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
 	defaultAddressScope = waddrmgr.KeyScopeBIP0044
+
+	// defaultPublicPassword is the well-known passphrase used for the public
+	// data of wallets created without a public passphrase.
+	defaultPublicPassword = "/flc/public"
+
+	// backupQuizWords is the number of mnemonic words asked to verify a
+	// backup.
+	backupQuizWords = 3
+
+	// privatePassEnv and seedPassEnv name the environment variables holding
+	// the private and BIP39 passphrases when no terminal is available.
+	privatePassEnv = "FCLI_PRIVPASS"
+	seedPassEnv    = "FCLI_BIP39_PASSPHRASE"
+
+	// birthdayMargin is subtracted from a birthday date to cover block
+	// timestamps drifting from the wall clock.
+	birthdayMargin = 48 * time.Hour
+
+	// restoreLookahead is the number of addresses per branch checked for
+	// history after restoring with a BIP39 passphrase.
+	restoreLookahead uint32 = 20
+
+	// gapLimitWarning is the share of the gap limit above which a run of
+	// unused addresses is reported.
+	gapLimitWarning = 0.8
 )
 
+// CreateOptions holds the settings of a new wallet.
+type CreateOptions struct {
+	// PublicDefault keeps the well-known public passphrase, leaving the
+	// public data readable.
+	PublicDefault bool
+
+	// SeedPassphrase protects the seed with a BIP39 passphrase.
+	SeedPassphrase bool
+
+	// Language selects the wordlist of the mnemonic.
+	Language string
+
+	// Words is the number of words of the mnemonic.
+	Words int
+
+	// SkipVerify does not ask for the mnemonic words after creation, the
+	// backup stays unverified.
+	SkipVerify bool
+
+	// SplitThreshold and SplitShares, when set, also split the new seed
+	// into SLIP-39 shares, any SplitThreshold of which restore the wallet.
+	SplitThreshold int
+	SplitShares    int
+
+	// SharePassphrase protects the shares with a passphrase.
+	SharePassphrase bool
+}
+
+// RestoreOptions holds the settings of a restored wallet.
+type RestoreOptions struct {
+	// PublicDefault keeps the well-known public passphrase, leaving the
+	// public data readable.
+	PublicDefault bool
+
+	// SeedPassphrase derives the seed with a BIP39 passphrase.
+	SeedPassphrase bool
+
+	// Language selects the wordlist of the mnemonic, it is detected from the
+	// words when empty.
+	Language string
+
+	// Words is the expected number of words of the mnemonic, any valid count
+	// is accepted when zero.
+	Words int
+
+	// Shares restores from SLIP-39 shares instead of a mnemonic.
+	Shares bool
+
+	// SharePassphrase decrypts the secret of the shares with a passphrase.
+	SharePassphrase bool
+
+	// Hex restores from the hex encoded seed instead of a mnemonic.
+	Hex bool
+
+	// MnemonicFile holds the mnemonic or hex seed, "-" for standard input.
+	MnemonicFile string
+
+	// PrivatePassFile holds the private passphrase of the restored wallet.
+	PrivatePassFile string
+
+	// SeedPassphraseFile holds the BIP39 passphrase, it implies
+	// SeedPassphrase.
+	SeedPassphraseFile string
+
+	// Birthday is the date (YYYY-MM-DD) or block height from which the
+	// wallet may have history, the whole chain is scanned when empty.
+	Birthday string
+
+	// GapLimit is stored in the wallet in place of the default gap limit
+	// when not zero.
+	GapLimit uint32
+
+	// Recover discovers the used addresses and their history from the
+	// Electrum server right after the restoration.
+	Recover bool
+}
+
 type WalletCliHandler struct {
 	*walletmgr.WalletService
 	cfg     *Config
@@ -29,11 +137,16 @@ type WalletCliHandler struct {
 
 func NewWalletCliHandler(network *chaincfg.Params, cfg *Config) *WalletCliHandler {
 
+	pubPass := cfg.PublicPassword
+	if pubPass == "" {
+		pubPass = defaultPublicPassword
+	}
+
 	params := &walletmgr.WalletParams{
 		Network:        network,
-		Path:           cfg.WalletDir,
+		Path:           cfg.WalletPath,
 		Timeout:        cfg.DBTimeout,
-		PublicPassword: cfg.PublicPassword,
+		PublicPassword: pubPass,
 		AddressScope:   defaultAddressScope,
 		ElectrumServer: cfg.ElectrumServer,
 		AccountID:      cfg.AccountID,
@@ -46,7 +159,7 @@ func NewWalletCliHandler(network *chaincfg.Params, cfg *Config) *WalletCliHandle
 	}
 }
 
-func (wch *WalletCliHandler) CreateWallet() {
+func (wch *WalletCliHandler) CreateWallet(opts CreateOptions) {
 	exists, err := wch.WalletExists()
 	if err != nil {
 		log.Fatalf("unable to load wallet: %v", err)
@@ -55,9 +168,36 @@ func (wch *WalletCliHandler) CreateWallet() {
 		log.Fatalf("A wallet already exists in the specified directory (%s)", wch.cfg.WalletDir)
 	}
 
+	seedLen, err := walletmgr.SeedLenForWords(opts.Words)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := walletmgr.SetMnemonicLanguage(opts.Language); err != nil {
+		log.Fatal(err)
+	}
+	split := opts.SplitShares != 0 || opts.SplitThreshold != 0
+	if split {
+		if err := slip39.CheckSplit(opts.SplitThreshold, opts.SplitShares); err != nil {
+			log.Fatalf("invalid --split-threshold or --split-shares: %v", err)
+		}
+	} else if opts.SharePassphrase {
+		log.Fatal("--share-passphrase needs --split-shares")
+	}
+
 	privPass := ReadPassword("Enter a private password to secure your wallet: ", true)
 
-	hex, words, err := wch.WalletService.Create(hdkeychain.RecommendedSeedLen, wch.cfg.AccountName, string(privPass))
+	if wch.cfg.PublicPassword == "" && !opts.PublicDefault {
+		wch.readNewPublicPassword()
+	}
+
+	seedPass := readSeedPassphrase(opts.SeedPassphrase, true)
+
+	var sharePass []byte
+	if opts.SharePassphrase {
+		sharePass = ReadPassword("Enter the passphrase protecting the shares: ", true)
+	}
+
+	seedHex, words, err := wch.WalletService.Create(seedLen, wch.cfg.AccountName, string(privPass), seedPass)
 	if err != nil {
 		log.Fatalf("unable to create wallet: %v", err)
 	}
@@ -66,19 +206,121 @@ func (wch *WalletCliHandler) CreateWallet() {
 	fmt.Println("|                                         |")
 	for i := 0; i < len(words); i += 4 {
 		line := words[i:min(i+4, len(words))]
-		fmt.Printf("| %-39s |\n", strings.Join(line, " "))
+		fmt.Printf("| %-39s |\n", norm.NFC.String(strings.Join(line, " ")))
 	}
 	fmt.Println("|                                         |")
 	fmt.Println("==========================================")
-	fmt.Printf("  Hex: %s\n", hex)
+	fmt.Printf("  Hex: %s\n", seedHex)
 	fmt.Println("==========================================")
 
 	fmt.Println("\nKeep this mnemonic safe! If lost, you cannot recover your wallet.")
+	if seedPass != nil {
+		fmt.Println("The BIP39 passphrase is required along with the mnemonic, store it separately.")
+	}
+
+	if split {
+		seed, err := hex.DecodeString(seedHex)
+		if err != nil {
+			log.Fatalf("unable to split seed: %v", err)
+		}
+		printShares(opts.SplitThreshold, opts.SplitShares, seed, sharePass)
+		fmt.Println()
+	}
 
 	fmt.Println("Wallet created successfully!")
+
+	wch.recordCreationHeight()
+
+	if opts.SkipVerify {
+		fmt.Println("Backup not verified, run verify-backup once the mnemonic is stored.")
+		return
+	}
+	wch.backupQuiz(words)
 }
 
-func (wch *WalletCliHandler) RestoreWallet() {
+// backupQuiz clears the mnemonic from the screen and asks for randomly chosen
+// words. The backup is marked verified only when every answer is right.
+func (wch *WalletCliHandler) backupQuiz(words []string) {
+	WaitForEnter("\nWrite down the mnemonic, then press Enter to verify your backup...")
+	ClearScreen()
+
+	positions := rand.Perm(len(words))[:min(backupQuizWords, len(words))]
+	sort.Ints(positions)
+
+	fmt.Println("Enter the requested words of your mnemonic.")
+	correct := true
+	for _, pos := range positions {
+		answer, err := ReadLine(fmt.Sprintf("Word #%d: ", pos+1), func(s string) error {
+			if s == "" {
+				return fmt.Errorf("word cannot be empty")
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Backup verification cancelled, run verify-backup to try again.")
+			return
+		}
+		if norm.NFKD.String(strings.ToLower(answer)) != words[pos] {
+			correct = false
+		}
+	}
+
+	if !correct {
+		fmt.Println("Backup verification failed: at least one word is wrong.")
+		fmt.Println("Check your written mnemonic and run verify-backup, the wallet stays unverified until then.")
+		return
+	}
+
+	if err := wch.SetBackupVerified(true); err != nil {
+		log.Fatalf("unable to record backup verification: %v", err)
+	}
+	fmt.Println("Backup verified!")
+}
+
+// VerifyBackup checks a mnemonic against the opened wallet and marks its
+// backup verified when it matches. The wallet does not keep its mnemonic, so
+// the whole phrase is asked for.
+func (wch *WalletCliHandler) VerifyBackup(seedPassphrase bool) {
+	verified, err := wch.BackupVerified()
+	if err != nil {
+		log.Fatalf("unable to read wallet metadata: %v", err)
+	}
+	if verified {
+		fmt.Println("The backup of this wallet is already verified, checking it again.")
+	}
+
+	wch.readWalletSeed(seedPassphrase)
+
+	if err := wch.SetBackupVerified(true); err != nil {
+		log.Fatalf("unable to record backup verification: %v", err)
+	}
+	fmt.Println("Backup verified!")
+}
+
+// readWalletSeed reads the mnemonic of the opened wallet and returns its seed,
+// exiting when the mnemonic belongs to another wallet.
+func (wch *WalletCliHandler) readWalletSeed(seedPassphrase bool) []byte {
+	entropy, _, err := walletmgr.EntropyFromMnemonic(ReadMnemonic(), "")
+	if err != nil {
+		log.Fatalf("Invalid mnemonic: %v", err)
+	}
+
+	seed, err := walletmgr.SeedFromEntropy(entropy, readSeedPassphrase(seedPassphrase, false))
+	if err != nil {
+		log.Fatalf("Invalid mnemonic: %v", err)
+	}
+
+	match, err := wch.MatchesSeed(seed)
+	if err != nil {
+		log.Fatalf("unable to check mnemonic: %v", err)
+	}
+	if !match {
+		log.Fatal("the mnemonic does not match this wallet")
+	}
+	return seed
+}
+
+func (wch *WalletCliHandler) RestoreWallet(opts RestoreOptions) {
 	exists, err := wch.WalletExists()
 	if err != nil {
 		log.Fatalf("unable to load wallet: %v", err)
@@ -88,20 +330,242 @@ func (wch *WalletCliHandler) RestoreWallet() {
 		log.Fatalf("wallet restoration failed: %v", err)
 	}
 
-	mnemonic := ReadMnemonic()
+	if opts.SeedPassphraseFile != "" {
+		opts.SeedPassphrase = true
+	}
+	if (opts.Shares || opts.Hex) && opts.SeedPassphrase {
+		log.Fatal("a BIP39 passphrase only applies to a mnemonic")
+	}
+	if opts.Shares && opts.Hex {
+		log.Fatal("--shares and --hex cannot be used together")
+	}
 
-	seed, err := bip39.EntropyFromMnemonic(mnemonic)
-	if err != nil {
-		log.Fatalf("Invalid mnemonic: %v", err)
+	birthday, birthdayBlock := wch.resolveBirthday(opts.Birthday)
+
+	var seed []byte
+	var hexSeed string
+	switch {
+	case opts.Shares:
+		seed = seedFromShares(opts.SharePassphrase)
+	case opts.Hex:
+		hexSeed = readHexSeed(opts.MnemonicFile)
+	default:
+		seed = seedFromMnemonic(opts)
 	}
 
-	privPass := ReadPassword("Enter a private password to secure your wallet: ", true)
+	privPass := readSecret("Enter a private password to secure your wallet: ", "--privpass-file", opts.PrivatePassFile, privatePassEnv, true)
 
-	if err := wch.WalletService.RestoreWallet(seed, privPass, wch.cfg.AccountName); err != nil {
+	if wch.cfg.PublicPassword == "" && !opts.PublicDefault {
+		if !IsTerminal() {
+			log.Fatal("cannot prompt for the public passphrase without a terminal, use --pubpass-file, FCLI_PUBPASS or --no-pubpass")
+		}
+		wch.readNewPublicPassword()
+	}
+
+	wch.UseGapLimit(opts.GapLimit)
+	if opts.Hex {
+		err = wch.WalletService.RestoreByHex(hexSeed, wch.cfg.AccountName, string(privPass), birthday)
+	} else {
+		err = wch.WalletService.RestoreWallet(seed, privPass, wch.cfg.AccountName, birthday)
+	}
+	if err != nil {
 		log.Fatalf("wallet restoration failed: %v", err)
 	}
 
+	if opts.GapLimit > 0 {
+		wch.PersistGapLimit(opts.GapLimit)
+	}
+
+	if birthdayBlock != nil {
+		if err := wch.SetBirthdayBlock(*birthdayBlock); err != nil {
+			log.Fatalf("unable to record wallet birthday: %v", err)
+		}
+	}
+
+	// The mnemonic was just typed in, the backup is proven to exist.
+	if err := wch.SetBackupVerified(true); err != nil {
+		log.Fatalf("unable to record backup verification: %v", err)
+	}
+
 	fmt.Println("Wallet restored successfully!")
+
+	if opts.Recover {
+		wch.Recover()
+		return
+	}
+
+	if opts.SeedPassphrase || opts.SharePassphrase {
+		wch.checkRestoredHistory()
+	}
+}
+
+// resolveBirthday parses a --birthday value, either a block height or a date.
+// A height is looked up on the Electrum server, the block before it is
+// returned so that the birthday block itself gets scanned.
+func (wch *WalletCliHandler) resolveBirthday(value string) (time.Time, *waddrmgr.BlockStamp) {
+	if value == "" {
+		fmt.Println("No birthday given, the wallet history is scanned from the genesis block.")
+		return time.Time{}, nil
+	}
+
+	if height, err := strconv.ParseUint(value, 10, 31); err == nil {
+		if _, err := ValidateAndNormalizeURI(wch.cfg.ElectrumServer, 50001); err != nil {
+			log.Fatal("a birthday height requires --electserver to look up the block")
+		}
+
+		block, err := wch.FetchBlockStamp(max(int32(height)-1, 0))
+		if err != nil {
+			log.Fatalf("unable to fetch birthday block %d: %v", height, err)
+		}
+		return block.Timestamp, block
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		log.Fatalf("invalid birthday %q, expected a date (YYYY-MM-DD) or a block height", value)
+	}
+	if date.After(time.Now()) {
+		log.Fatalf("birthday %s is in the future", value)
+	}
+	return date.Add(-birthdayMargin), nil
+}
+
+// recordCreationHeight stores the current chain tip as the birthday block of
+// a new wallet. Without an Electrum server, the block is located from the
+// creation time at the first sync instead.
+func (wch *WalletCliHandler) recordCreationHeight() {
+	if _, err := ValidateAndNormalizeURI(wch.cfg.ElectrumServer, 50001); err != nil {
+		return
+	}
+
+	tip, err := wch.FetchBlockStamp(-1)
+	if err != nil {
+		log.Printf("unable to fetch the creation height: %v", err)
+		return
+	}
+
+	if err := wch.SetBirthdayBlock(*tip); err != nil {
+		log.Fatalf("unable to record wallet birthday: %v", err)
+	}
+	fmt.Printf("Wallet birthday: block %d\n", tip.Height)
+}
+
+// seedFromMnemonic reads a BIP39 mnemonic and derives the wallet seed.
+func seedFromMnemonic(opts RestoreOptions) []byte {
+	mnemonic := readSeedInput(opts.MnemonicFile, ReadMnemonic)
+
+	if count := len(strings.Fields(mnemonic)); opts.Words != 0 && count != opts.Words {
+		log.Fatalf("Invalid mnemonic: expected %d words, got %d", opts.Words, count)
+	}
+
+	entropy, language, err := walletmgr.EntropyFromMnemonic(mnemonic, opts.Language)
+	if err != nil {
+		log.Fatalf("Invalid mnemonic: %v", err)
+	}
+	if opts.Language == "" {
+		fmt.Printf("Mnemonic language: %s\n", language)
+	}
+
+	var seedPass *string
+	if opts.SeedPassphrase {
+		pass := string(readSecret("Enter the BIP39 passphrase (25th word): ", "--bip39-passphrase-file", opts.SeedPassphraseFile, seedPassEnv, false))
+		seedPass = &pass
+	}
+
+	seed, err := walletmgr.SeedFromEntropy(entropy, seedPass)
+	if err != nil {
+		log.Fatalf("Invalid mnemonic: %v", err)
+	}
+	return seed
+}
+
+// readHexSeed reads the hex encoded seed printed at wallet creation.
+func readHexSeed(path string) string {
+	input := readSeedInput(path, func() string {
+		hexSeed, err := ReadLine("Enter your hex seed: ", func(s string) error {
+			_, err := walletmgr.SeedFromHex(s)
+			return err
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return hexSeed
+	})
+
+	if _, err := walletmgr.SeedFromHex(input); err != nil {
+		log.Fatal(err)
+	}
+	return input
+}
+
+// readSeedInput returns the backup read from the file, from standard input
+// when it is not a terminal, or from the interactive prompt.
+func readSeedInput(path string, prompt func() string) string {
+	if path == "" && !IsTerminal() {
+		path = "-"
+	}
+	if path == "" {
+		return prompt()
+	}
+
+	data, err := ReadSecretFile(path)
+	if err != nil {
+		log.Fatalf("unable to read seed backup: %v", err)
+	}
+	return string(data)
+}
+
+// readSecret returns a secret read from a file, an environment variable or
+// the terminal, in that order. Without a terminal one of the first two must
+// be provided.
+func readSecret(prompt, flag, path, env string, confirm bool) []byte {
+	if path != "" {
+		secret, err := ReadSecretFile(path)
+		if err != nil {
+			log.Fatalf("unable to read %s: %v", flag, err)
+		}
+		return secret
+	}
+
+	if secret, ok := os.LookupEnv(env); ok {
+		return []byte(secret)
+	}
+
+	if !IsTerminal() {
+		log.Fatalf("cannot prompt without a terminal, use %s or set %s", flag, env)
+	}
+	return ReadPassword(prompt, confirm)
+}
+
+// checkRestoredHistory warns when a restored wallet shows no on-chain
+// activity, the usual symptom of a mistyped BIP39 passphrase.
+func (wch *WalletCliHandler) checkRestoredHistory() {
+	if _, err := ValidateAndNormalizeURI(wch.cfg.ElectrumServer, 50001); err != nil {
+		fmt.Println("Pass --electserver to check that the passphrase opens the wallet you expect.")
+		return
+	}
+
+	used, err := wch.HasHistory(restoreLookahead)
+	if err != nil {
+		log.Printf("unable to check wallet history: %v", err)
+		return
+	}
+
+	if !used {
+		fmt.Printf("WARNING: none of the first %d addresses has any history.\n", restoreLookahead)
+		fmt.Println("Any passphrase opens a valid but different wallet, double-check it if you expected funds.")
+	}
+}
+
+// readSeedPassphrase asks for the BIP39 passphrase when enabled. A nil result
+// selects the legacy seed derivation.
+func readSeedPassphrase(enabled, confirm bool) *string {
+	if !enabled {
+		return nil
+	}
+
+	seedPass := string(ReadPassword("Enter the BIP39 passphrase (25th word): ", confirm))
+	return &seedPass
 }
 
 func (wch *WalletCliHandler) RequireWallet() {
@@ -116,8 +580,83 @@ func (wch *WalletCliHandler) RequireWallet() {
 	}
 
 	if err := wch.OpenWallet(); err != nil {
-		log.Fatalf("opening failed: %v", err)
+		if !waddrmgr.IsError(err, waddrmgr.ErrWrongPassphrase) {
+			log.Fatalf("opening failed: %v", err)
+		}
+		if wch.cfg.PublicPassword != "" {
+			log.Fatal("opening failed: incorrect public passphrase")
+		}
+
+		// The public data is encrypted, ask for its passphrase
+		pubPass := ReadPassword("Enter the public passphrase to open the wallet: ", false)
+		wch.SetPublicPassword(string(pubPass))
+		if err := wch.OpenWallet(); err != nil {
+			log.Fatalf("opening failed: %v", err)
+		}
+	}
+}
+
+// PersistGapLimit stores the gap limit in the wallet for later recoveries,
+// syncs and rescans.
+func (wch *WalletCliHandler) PersistGapLimit(limit uint32) {
+	if err := wch.SaveGapLimit(limit); err != nil {
+		log.Fatalf("unable to save gap limit: %v", err)
+	}
+	fmt.Printf("Gap limit of %d addresses saved in the wallet.\n", limit)
+}
+
+// warnGapLimit reports a run of unused addresses close to the gap limit, as
+// funds received past the limit would be missed by a recovery.
+func (wch *WalletCliHandler) warnGapLimit() {
+	gap, err := wch.LargestAddressGap()
+	if err != nil {
+		log.Fatalf("unable to check address gap: %v", err)
+	}
+
+	limit := wch.GapLimit()
+	if float64(gap) < float64(limit)*gapLimitWarning {
+		return
+	}
+
+	fmt.Printf("WARNING: %d unused addresses in a row were found before a used one, close to the gap limit of %d.\n", gap, limit)
+	fmt.Println("Addresses used further away would be missed, raise the limit with --gap-limit.")
+}
+
+// ChangePublicPassword encrypts the public data of an existing wallet with a
+// new passphrase, or reverts it to the well-known default one.
+func (wch *WalletCliHandler) ChangePublicPassword(useDefault bool) {
+	oldPass := wch.PublicPassword()
+
+	newPass := defaultPublicPassword
+	if !useDefault {
+		pubPass := ReadPassword("Enter the new public passphrase: ", true)
+		if len(pubPass) == 0 {
+			log.Fatal("public passphrase cannot be empty, use --default to drop the encryption")
+		}
+		newPass = string(pubPass)
+	}
+
+	if err := wch.Wallet.ChangePublicPassphrase([]byte(oldPass), []byte(newPass)); err != nil {
+		log.Fatalf("unable to change public passphrase: %v", err)
+	}
+	wch.SetPublicPassword(newPass)
+
+	if useDefault {
+		fmt.Println("Public data is no longer encrypted.")
+	} else {
+		fmt.Println("Public passphrase updated! It is now required to read the wallet.")
+	}
+}
+
+// readNewPublicPassword asks for the passphrase encrypting the public data of
+// a new wallet. An empty answer keeps the well-known default passphrase.
+func (wch *WalletCliHandler) readNewPublicPassword() {
+	pubPass := ReadPassword("Enter a public passphrase to encrypt addresses and history (empty to skip): ", true)
+	if len(pubPass) == 0 {
+		fmt.Println("No public passphrase set, anyone with access to the wallet file can read its addresses and balance.")
+		return
 	}
+	wch.SetPublicPassword(string(pubPass))
 }
 
 func (wch *WalletCliHandler) Config() *Config {
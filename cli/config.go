@@ -7,12 +7,15 @@ package cli
 import "time"
 
 type Config struct {
-	WalletDir      string        `short:"w" long:"walletdir" description:"Directory for the wallet.db"`
-	RegressionTest bool          `long:"regtest" description:"Use the regression test network"`
-	Testnet        bool          `long:"testnet" description:"Use the test network"`
-	PublicPassword string        `long:"pubpass" description:"Public password used to encrypt public data."`
-	DBTimeout      time.Duration `short:"t" long:"timeout" description:"Timeout duration (in seconds) for wallet connection"`
-	ElectrumServer string        `short:"e" long:"electserver" description:"Electrum server host:port"`
-	AccountID      uint32        `long:"id" description:"Account ID (default '1' is used instead)"`
-	AccountName    string        `long:"name" description:"Account Name (default 'myfloki' is used instead)"`
+	WalletDir          string        `short:"w" long:"walletdir" description:"Directory for the wallet.db"`
+	Wallet             string        `long:"wallet" description:"Name of the wallet to use within the wallet directory (see 'wallets list')"`
+	WalletPath         string        `no-flag:"true"`
+	RegressionTest     bool          `long:"regtest" description:"Use the regression test network"`
+	Testnet            bool          `long:"testnet" description:"Use the test network"`
+	PublicPassword     string        `long:"pubpass" description:"Public password used to encrypt public data (or set FCLI_PUBPASS)"`
+	PublicPasswordFile string        `long:"pubpass-file" description:"File containing the public password"`
+	DBTimeout          time.Duration `short:"t" long:"timeout" description:"Timeout duration (in seconds) for wallet connection"`
+	ElectrumServer     string        `short:"e" long:"electserver" description:"Electrum server host:port"`
+	AccountID          uint32        `long:"id" description:"Account ID (default '1' is used instead)"`
+	AccountName        string        `long:"name" description:"Account Name (default 'myfloki' is used instead)"`
 }
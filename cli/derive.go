@@ -0,0 +1,204 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	. "github.com/flokiorg/fcli/utils"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// maxDerivedKeys bounds the number of keys a derive path can match.
+const maxDerivedKeys = 100000
+
+// deriveTypes maps the address types of the derive command to the type and
+// the scope of the wallet accounts deriving them.
+var deriveTypes = map[string]struct {
+	addrType waddrmgr.AddressType
+	scope    waddrmgr.KeyScope
+}{
+	"p2pkh":       {waddrmgr.PubKeyHash, waddrmgr.KeyScopeBIP0044},
+	"p2sh-p2wpkh": {waddrmgr.NestedWitnessPubKey, waddrmgr.KeyScopeBIP0049Plus},
+	"p2wpkh":      {waddrmgr.WitnessPubKey, waddrmgr.KeyScopeBIP0084},
+	"p2tr":        {waddrmgr.TaprootPubKey, waddrmgr.KeyScopeBIP0086},
+}
+
+type DeriveOptions struct {
+	// Key is the extended public or private key to derive from, in any
+	// SLIP-132 format. The account of the wallet of the address type is
+	// used when empty.
+	Key string
+
+	// Path is the BIP32 path relative to the key, with ranges such as
+	// "0-1/0-19".
+	Path string
+
+	// Type is the address type, see deriveTypes. It follows the format of
+	// Key when empty, p2pkh for the wallet.
+	Type string
+
+	// WIF prints the private keys, from an extended private key or from
+	// the wallet once unlocked.
+	WIF bool
+
+	// PrivatePassFile holds the private passphrase of the wallet.
+	PrivatePassFile string
+}
+
+// Derive prints the path, address and public key of every key matched by a
+// path below an extended key or a wallet account, with the private key in WIF
+// when asked.
+func (wch *WalletCliHandler) Derive(opts DeriveOptions) {
+	var (
+		key    *hdkeychain.ExtendedKey
+		format KeyFormat
+		prefix string
+		err    error
+	)
+	if opts.Key != "" {
+		if key, format, err = ParseExtendedKey(opts.Key, wch.network); err != nil {
+			log.Fatalf("invalid extended key: %v", err)
+		}
+	}
+
+	// The address type follows the format of the key unless given.
+	if opts.Type == "" {
+		opts.Type = "p2pkh"
+		if opts.Key != "" {
+			if format.Multisig {
+				log.Fatalf("%s is a multisig cosigner key, give the address type with --type", format.Public)
+			}
+			opts.Type = format.Script
+		}
+	}
+	kind, ok := deriveTypes[opts.Type]
+	if !ok {
+		log.Fatalf("unsupported address type %q", opts.Type)
+	}
+	paths, err := ExpandDerivationPath(opts.Path, maxDerivedKeys)
+	if err != nil {
+		log.Fatalf("invalid path %q: %v", opts.Path, err)
+	}
+
+	if opts.Key != "" {
+		if opts.WIF && !key.IsPrivate() {
+			log.Fatal("private keys can only be derived from an extended private key")
+		}
+		if key.Depth() == 0 {
+			prefix = "m/"
+		}
+	} else {
+		if opts.WIF {
+			privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", opts.PrivatePassFile, privatePassEnv, false)
+			if err := wch.Unlock(privPass, nil); err != nil {
+				log.Fatalf("Failed to unlock wallet: %v", err)
+			}
+			defer wch.Lock()
+		}
+
+		props, err := wch.AccountProperties(kind.scope, wch.cfg.AccountID)
+		if err != nil {
+			log.Fatalf("unable to fetch account %d of %s: %v", wch.cfg.AccountID, kind.scope, err)
+		}
+		key = props.AccountPubKey
+		if opts.WIF {
+			key = props.AccountPrivKey
+		}
+		if key == nil {
+			log.Fatalf("no key for account %d of %s", wch.cfg.AccountID, kind.scope)
+		}
+		prefix = fmt.Sprintf("m/%d'/%d'/%d'/", kind.scope.Purpose, kind.scope.Coin, props.AccountNumber)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, path := range paths {
+		child := key
+		for _, index := range path {
+			if child, err = child.Derive(index); err != nil {
+				log.Fatalf("unable to derive %s: %v", FormatDerivationPath(path), err)
+			}
+		}
+
+		addr, err := KeyAddress(child, kind.addrType, wch.network)
+		if err != nil {
+			log.Fatalf("unable to derive %s: %v", FormatDerivationPath(path), err)
+		}
+		pubKey, err := child.ECPubKey()
+		if err != nil {
+			log.Fatalf("unable to derive %s: %v", FormatDerivationPath(path), err)
+		}
+
+		line := fmt.Sprintf("%s%s\t%s\t%s", prefix, FormatDerivationPath(path), addr, hex.EncodeToString(pubKey.SerializeCompressed()))
+		if opts.WIF {
+			privKey, err := child.ECPrivKey()
+			if err != nil {
+				log.Fatalf("unable to derive %s: %v", FormatDerivationPath(path), err)
+			}
+			wif, err := chainutil.NewWIF(privKey, wch.network, true)
+			if err != nil {
+				log.Fatalf("unable to encode private key: %v", err)
+			}
+			line += "\t" + wif.String()
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
+
+// ConvertKey prints the depth, fingerprints and child number of an extended
+// key in any SLIP-132 format, then the key in the format named to, or in every
+// format of the network when to is empty.
+func (wch *WalletCliHandler) ConvertKey(strKey, to string) {
+	key, format, err := ParseExtendedKey(strKey, wch.network)
+	if err != nil {
+		log.Fatalf("invalid extended key: %v", err)
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		log.Fatalf("invalid extended key: %v", err)
+	}
+
+	name := format.Public
+	if key.IsPrivate() {
+		name = format.Private
+	}
+	child := fmt.Sprintf("%d", key.ChildIndex())
+	if key.ChildIndex() >= hdkeychain.HardenedKeyStart {
+		child = fmt.Sprintf("%d' (%d)", key.ChildIndex()-hdkeychain.HardenedKeyStart, key.ChildIndex())
+	}
+	fmt.Printf("Format:             %s (%s, %s)\n", name, format.Script, wch.network.Name)
+	fmt.Printf("Private:            %t\n", key.IsPrivate())
+	fmt.Printf("Depth:              %d\n", key.Depth())
+	fmt.Printf("Fingerprint:        %x\n", chainutil.Hash160(pubKey.SerializeCompressed())[:4])
+	fmt.Printf("Parent fingerprint: %08x\n", key.ParentFingerprint())
+	fmt.Printf("Child number:       %s\n", child)
+
+	formats := KeyFormats(wch.network)
+	if to != "" {
+		target, err := KeyFormatByName(to, wch.network)
+		if err != nil {
+			log.Fatal(err)
+		}
+		formats = []KeyFormat{target}
+	}
+	for _, target := range formats {
+		converted, err := FormatExtendedKey(key, target)
+		if err != nil {
+			log.Fatalf("unable to convert key: %v", err)
+		}
+		name := target.Public
+		if key.IsPrivate() {
+			name = target.Private
+		}
+		fmt.Printf("%-20s%s\n", name+":", converted)
+	}
+}
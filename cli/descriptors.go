@@ -0,0 +1,179 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	walletmgr "github.com/flokiorg/fcli/wallet"
+)
+
+// ExportDescriptors prints the output descriptors of the accounts and of the
+// watch-only addresses, with the private keys of the accounts when private is
+// set.
+func (wch *WalletCliHandler) ExportDescriptors(private bool, privPassFile string) {
+	if private {
+		privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", privPassFile, privatePassEnv, false)
+		if err := wch.Unlock(privPass, nil); err != nil {
+			log.Fatalf("Failed to unlock wallet: %v", err)
+		}
+		defer wch.Lock()
+	}
+
+	accounts, err := wch.WalletService.ExportDescriptors(private)
+	if err != nil {
+		log.Fatalf("unable to export descriptors: %v", err)
+	}
+	watched, err := wch.WatchedAddresses()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only addresses: %v", err)
+	}
+
+	if private {
+		fmt.Fprintln(os.Stderr, "WARNING: the descriptors below hold the private keys of the wallet, anyone reading them can spend its funds.")
+	}
+	for _, account := range accounts {
+		if account.WatchOnly {
+			fmt.Printf("# %s account %d %q (watch-only)\n", account.Scope, account.Account, account.Name)
+		} else {
+			fmt.Printf("# %s account %d %q\n", account.Scope, account.Account, account.Name)
+		}
+		fmt.Println(account.External)
+		fmt.Println(account.Internal)
+	}
+	if len(watched) > 0 {
+		fmt.Println("# watch-only addresses")
+	}
+	for _, wad := range watched {
+		addr := wch.decodeAddress(wad.Address)
+		desc, err := walletmgr.AddressDescriptor(addr)
+		if err != nil {
+			log.Fatalf("unable to export %s: %v", addr, err)
+		}
+		fmt.Println(desc)
+	}
+}
+
+// ImportDescriptors creates watch-only accounts from account descriptors,
+// grouped by key, and watches the addresses of addr() descriptors. The
+// descriptors are read from the arguments or from path, "-" for standard
+// input, skipping blank and comment lines. History is fetched right away when
+// rescan is set.
+func (wch *WalletCliHandler) ImportDescriptors(args []string, path, name string, rescan bool) {
+	lines := args
+	if path != "" {
+		lines = append(lines, readDescriptorFile(path)...)
+	}
+	if len(lines) == 0 {
+		log.Fatal("no descriptor given")
+	}
+
+	var keys []string
+	groups := make(map[string][]*walletmgr.Descriptor)
+	var addresses []*walletmgr.Descriptor
+	for _, line := range lines {
+		desc, err := walletmgr.ParseDescriptor(line, wch.network)
+		if err != nil {
+			log.Fatalf("invalid descriptor %s: %v", line, err)
+		}
+		if desc.Address != nil {
+			addresses = append(addresses, desc)
+			continue
+		}
+		key := desc.Key.String()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], desc)
+	}
+
+	for i, key := range keys {
+		accountName := name
+		if len(keys) > 1 {
+			accountName = fmt.Sprintf("%s-%d", name, i+1)
+		}
+
+		props, addrs, err := wch.WalletService.ImportDescriptors(accountName, groups[key])
+		if err != nil {
+			log.Fatalf("unable to import account %s: %v", accountName, err)
+		}
+		fmt.Printf("Imported watch-only account %d %q in %s, %d addresses derived\n",
+			props.AccountNumber, props.AccountName, props.KeyScope, len(addrs))
+
+		if !rescan {
+			continue
+		}
+		stats, err := wch.ScanAddresses(addrs, 0)
+		if err != nil {
+			log.Fatalf("unable to fetch history: %v", err)
+		}
+		balance, err := wch.AccountBalance(props.KeyScope, props.AccountNumber)
+		if err != nil {
+			log.Fatalf("unable to compute balance: %v", err)
+		}
+		fmt.Printf("  %d used addresses, balance %f\n", stats.Other.Used, balance.ToFLC())
+	}
+
+	for _, desc := range addresses {
+		if err := wch.AddWatchedAddress(desc.Address, 0); err != nil {
+			log.Fatalf("unable to watch %s: %v", desc.Address, err)
+		}
+		fmt.Printf("Watching %s\n", desc.Address)
+	}
+	if len(addresses) > 0 && rescan {
+		wch.refreshWatchOnly()
+		wch.printWatchOnlyBalance()
+	}
+
+	if !rescan {
+		fmt.Println("History will be fetched on the next rescan.")
+	}
+}
+
+// printWatchOnlyAccounts prints the balance of the watch-only accounts, if
+// any.
+func (wch *WalletCliHandler) printWatchOnlyAccounts() {
+	accounts, err := wch.WatchOnlyAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only accounts: %v", err)
+	}
+	for _, props := range accounts {
+		balance, err := wch.AccountBalance(props.KeyScope, props.AccountNumber)
+		if err != nil {
+			log.Fatalf("unable to compute balance: %v", err)
+		}
+		log.Printf("watch-only account %q (%s) balance: %f", props.AccountName, props.KeyScope, balance.ToFLC())
+	}
+}
+
+func readDescriptorFile(path string) []string {
+	file := os.Stdin
+	if path != "-" {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			log.Fatalf("unable to read descriptors: %v", err)
+		}
+		defer file.Close()
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("unable to read descriptors: %v", err)
+	}
+	return lines
+}
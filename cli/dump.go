@@ -0,0 +1,228 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/flokiorg/fcli/utils"
+	walletmgr "github.com/flokiorg/fcli/wallet"
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// dumpPassEnv names the environment variable holding the passphrase of wallet
+// dumps when no terminal is available.
+var dumpPassEnv = "FCLI_DUMP_PASSPHRASE"
+
+// DumpOptions holds the settings of a wallet dump.
+type DumpOptions struct {
+	// Plaintext writes the keys without encrypting the dump.
+	Plaintext bool
+
+	// PrivatePassFile holds the private passphrase of the wallet.
+	PrivatePassFile string
+
+	// PassphraseFile holds the passphrase of the dump.
+	PassphraseFile string
+}
+
+// DumpWallet writes every private key of the wallet to path, one per line
+// with its address, derivation path and label. The file is encrypted with a
+// passphrase unless opts.Plaintext is set.
+func (wch *WalletCliHandler) DumpWallet(path string, opts DumpOptions) {
+	if _, err := os.Stat(path); err == nil {
+		log.Fatalf("%s already exists", path)
+	}
+
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", opts.PrivatePassFile, privatePassEnv, false)
+	if err := wch.Unlock(privPass, nil); err != nil {
+		log.Fatalf("Failed to unlock wallet: %v", err)
+	}
+	records, err := wch.ExportKeys()
+	wch.Lock()
+	if err != nil {
+		log.Fatalf("unable to export keys: %v", err)
+	}
+
+	birthday, _, err := wch.Birthday()
+	if err != nil {
+		log.Fatalf("unable to read wallet birthday: %v", err)
+	}
+	height, hash := wch.currentState()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Wallet dump created by fcli %s\n", Version)
+	fmt.Fprintf(&buf, "# * Created on %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# * Network: %s\n", wch.ChainParams().Name)
+	fmt.Fprintf(&buf, "# * Best block at time of backup was %d (%s)\n", height, hash)
+	fmt.Fprintln(&buf, "#")
+	fmt.Fprintln(&buf, "# <wif> <birthday> label=<label> [change=1] # addr=<address> [hdkeypath=<path>]")
+	for _, record := range records {
+		fmt.Fprintf(&buf, "%s %s label=%s", record.WIF, birthday.UTC().Format(time.RFC3339), url.QueryEscape(record.Label))
+		if record.Internal {
+			buf.WriteString(" change=1")
+		}
+		fmt.Fprintf(&buf, " # addr=%s", record.Address)
+		if record.Path != "" {
+			fmt.Fprintf(&buf, " hdkeypath=%s", record.Path)
+		}
+		buf.WriteString("\n")
+	}
+	fmt.Fprintln(&buf, "# End of dump")
+
+	data := buf.Bytes()
+	if opts.Plaintext {
+		fmt.Println("WARNING: the dump is not encrypted, anyone reading it can spend the funds.")
+	} else {
+		passphrase := readSecret("Enter a passphrase to encrypt the dump: ", "--passphrase-file", opts.PassphraseFile, dumpPassEnv, true)
+		if data, err = walletmgr.SealDump(data, passphrase); err != nil {
+			log.Fatalf("unable to encrypt dump: %v", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatalf("unable to create dump: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		log.Fatalf("unable to write dump: %v", err)
+	}
+
+	fmt.Printf("%d keys written to %s\n", len(records), path)
+}
+
+// ImportWallet imports the private keys of a dump written by DumpWallet, with
+// their labels. Keys already in the wallet are skipped. The history of the
+// keys is fetched when rescan is set.
+func (wch *WalletCliHandler) ImportWallet(path string, opts DumpOptions, rescan bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("unable to read dump: %v", err)
+	}
+
+	if walletmgr.IsSealedDump(data) {
+		passphrase := readSecret("Enter the passphrase of the dump: ", "--passphrase-file", opts.PassphraseFile, dumpPassEnv, false)
+		if data, err = walletmgr.OpenDump(data, passphrase); err != nil {
+			log.Fatalf("unable to decrypt dump: %v", err)
+		}
+	}
+
+	records, err := parseDump(data, wch.ChainParams())
+	if err != nil {
+		log.Fatalf("invalid dump: %v", err)
+	}
+
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", opts.PrivatePassFile, privatePassEnv, false)
+	if err := wch.Unlock(privPass, nil); err != nil {
+		log.Fatalf("Failed to unlock wallet: %v", err)
+	}
+	defer wch.Lock()
+
+	var imported []chainutil.Address
+	var present int
+	for _, record := range records {
+		scope, err := walletmgr.ScopeForAddress(record.Address)
+		if err != nil {
+			log.Fatalf("unable to import %s: %v", record.Address, err)
+		}
+
+		addr, err := wch.ImportKey(scope, record.WIF, nil)
+		switch {
+		case waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress):
+			present++
+		case err != nil:
+			log.Fatalf("unable to import %s: %v", record.Address, err)
+		case addr.EncodeAddress() != record.Address.EncodeAddress():
+			log.Fatalf("key of %s imported as %s", record.Address, addr)
+		default:
+			imported = append(imported, addr)
+		}
+
+		if record.Label != "" {
+			if err := wch.SetAddressLabel(record.Address, record.Label); err != nil {
+				log.Fatalf("unable to label %s: %v", record.Address, err)
+			}
+		}
+	}
+
+	fmt.Printf("%d keys imported, %d already in the wallet\n", len(imported), present)
+
+	if len(imported) == 0 {
+		return
+	}
+	if !rescan {
+		fmt.Println("Run 'rescan --from-height 0' to fetch the history of the imported keys.")
+		return
+	}
+
+	fmt.Println("Fetching history of the imported keys...")
+	stats, err := wch.ScanAddresses(imported, 0)
+	if err != nil {
+		log.Fatalf("unable to fetch history: %v", err)
+	}
+	fmt.Printf("%d transactions found, balance %v\n", stats.Transactions, stats.Other.Funds)
+}
+
+// parseDump reads the keys of a plaintext wallet dump.
+func parseDump(data []byte, network *chaincfg.Params) ([]*walletmgr.KeyRecord, error) {
+	var records []*walletmgr.KeyRecord
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields, comment, _ := strings.Cut(text, "#")
+		values := strings.Fields(fields)
+		if len(values) < 2 {
+			return nil, fmt.Errorf("line %d: missing fields", line)
+		}
+
+		wif, err := chainutil.DecodeWIF(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if !wif.IsForNet(network) {
+			return nil, fmt.Errorf("line %d: key is not for %s", line, network.Name)
+		}
+
+		record := &walletmgr.KeyRecord{WIF: wif}
+		for _, field := range append(values[2:], strings.Fields(comment)...) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "label":
+				if record.Label, err = url.QueryUnescape(value); err != nil {
+					return nil, fmt.Errorf("line %d: invalid label: %v", line, err)
+				}
+			case "change":
+				record.Internal = value == "1"
+			case "addr":
+				if record.Address, err = chainutil.DecodeAddress(value, network); err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+			case "hdkeypath":
+				record.Path = value
+			}
+		}
+		if record.Address == nil {
+			return nil, fmt.Errorf("line %d: missing address", line)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
@@ -11,11 +11,14 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"strings"
 	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	. "github.com/flokiorg/fcli/utils"
+	walletmgr "github.com/flokiorg/fcli/wallet"
 	"github.com/flokiorg/go-flokicoin/chainjson"
 	"github.com/flokiorg/go-flokicoin/chainutil"
 	"github.com/flokiorg/walletd/chain/electrum"
@@ -32,20 +35,99 @@ func (wch *WalletCliHandler) GenerateNewAddress() {
 	log.Printf("Address: %s", addr.EncodeAddress())
 }
 
-func (wch *WalletCliHandler) ListAddresses() {
-	addrs, err := wch.AccountAddresses(wch.cfg.AccountID)
+type ListAddressesOptions struct {
+	// Internal and External keep only the change or the receive addresses.
+	Internal bool
+	External bool
+
+	// Used and Unused keep only the addresses that were paid, or not.
+	Used   bool
+	Unused bool
+
+	// MinBalance keeps the addresses holding at least that amount, any
+	// positive balance when WithBalance is set.
+	WithBalance bool
+	MinBalance  chainutil.Amount
+
+	// Sort orders the addresses by "path", "balance" or "received", the
+	// amounts from the largest.
+	Sort string
+}
+
+// ListAddresses prints the addresses of the account with their derivation
+// path, branch, usage, received total, balance and label.
+func (wch *WalletCliHandler) ListAddresses(opts ListAddressesOptions) {
+	addrs, err := wch.AccountAddressDetails(wch.cfg.AccountID)
 	if err != nil {
 		log.Fatalf("Failed to list addresses: %v", err)
 	}
+	labels, err := wch.AddressLabels()
+	if err != nil {
+		log.Fatalf("unable to fetch labels: %v", err)
+	}
+
+	var rows []*walletmgr.AddressDetails
+	for _, addr := range addrs {
+		internal := !addr.Imported && addr.Branch == waddrmgr.InternalBranch
+		switch {
+		case opts.Internal && !internal, opts.External && internal:
+		case opts.Used && !addr.Used, opts.Unused && addr.Used:
+		case opts.WithBalance && addr.Balance == 0:
+		case addr.Balance < opts.MinBalance:
+		default:
+			rows = append(rows, addr)
+		}
+	}
 
-	if len(addrs) == 0 {
-		log.Printf("No addresses found. Your wallet does not contain any generated addresses yet.")
-	} else {
-		log.Printf("List of available addresses:")
-		for _, addr := range addrs {
-			log.Printf("- %s", addr)
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch {
+		case a.Scope.Purpose != b.Scope.Purpose:
+			return a.Scope.Purpose < b.Scope.Purpose
+		case a.Imported != b.Imported:
+			return b.Imported
+		case a.Branch != b.Branch:
+			return a.Branch < b.Branch
+		}
+		return a.Index < b.Index
+	})
+	switch opts.Sort {
+	case "balance":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Balance > rows[j].Balance })
+	case "received":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Received > rows[j].Received })
+	}
+
+	if len(rows) == 0 {
+		if len(addrs) == 0 {
+			log.Printf("No addresses found. Your wallet does not contain any generated addresses yet.")
+		} else {
+			log.Printf("No address matches the filters.")
+		}
+		return
+	}
+
+	var total chainutil.Amount
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tBRANCH\tADDRESS\tUSED\tRECEIVED\tBALANCE\tLABEL")
+	for _, addr := range rows {
+		path, branch := addr.Path, "receive"
+		switch {
+		case addr.Imported:
+			path, branch = "imported", "-"
+		case addr.Branch == waddrmgr.InternalBranch:
+			branch = "change"
+		}
+		used := "no"
+		if addr.Used {
+			used = "yes"
 		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%f\t%f\t%s\n", path, branch, addr.Address, used,
+			addr.Received.ToFLC(), addr.Balance.ToFLC(), labels[addr.Address.EncodeAddress()])
+		total += addr.Balance
 	}
+	w.Flush()
+	fmt.Printf("%d addresses, balance %f\n", len(rows), total.ToFLC())
 }
 
 func (wch *WalletCliHandler) ListAccounts() {
@@ -84,52 +166,91 @@ func (wch *WalletCliHandler) ListAccounts() {
 
 }
 
-func (wch *WalletCliHandler) ImportWithWIF() {
-	strWif, err := ReadLine("Enter WIF: ", func(s string) error {
-		_, err := chainutil.DecodeWIF(s)
+// ImportKeyOptions holds the settings of a private key import.
+type ImportKeyOptions struct {
+	// WIFFile holds the key in WIF, "-" for standard input. The key is
+	// prompted for when empty.
+	WIFFile string
+
+	// PrivatePassFile holds the private passphrase of the wallet.
+	PrivatePassFile string
+
+	// Label is attached to the address of the key.
+	Label string
+
+	// RescanFrom is the height from which the history of the key is
+	// fetched, the rescan is skipped when negative.
+	RescanFrom int32
+}
+
+// ImportWithWIF adds a private key to the imported account and fetches the
+// history of its address from the Electrum server.
+func (wch *WalletCliHandler) ImportWithWIF(opts ImportKeyOptions) {
+	path := opts.WIFFile
+	if path == "" && !IsTerminal() {
+		path = "-"
+	}
+
+	var strWif string
+	if path == "" {
+		strWif = string(ReadPassword("Enter WIF: ", false))
+	} else {
+		data, err := ReadSecretFile(path)
 		if err != nil {
-			return err
+			log.Fatalf("unable to read wif: %v", err)
 		}
-		return nil
-	})
+		strWif = string(data)
+	}
+
+	wif, err := chainutil.DecodeWIF(strings.TrimSpace(strWif))
 	if err != nil {
 		log.Fatalf("unable to decode wif: %v", err)
 	}
+	if !wif.IsForNet(wch.ChainParams()) {
+		log.Fatalf("the key is not for %s", wch.ChainParams().Name)
+	}
 
-	wif, _ := chainutil.DecodeWIF(strWif)
-
-	privPass := ReadPassword("Enter the private password to unlock the wallet: ", false)
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", opts.PrivatePassFile, privatePassEnv, false)
 	if err := wch.Unlock(privPass, nil); err != nil {
 		log.Fatalf("Failed to unlock wallet: %v", err)
 	}
-	defer wch.Lock()
-	fmt.Println("importing...")
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		n1, n2, n3, _, err := wch.Watch()
-		for {
-			select {
-			case <-n1:
-				return
-			case <-n2:
-				// fmt.Printf("n2")
-			case <-n3:
-				// fmt.Printf("n3")
-			case <-err:
-			}
-		}
-	}()
-	addr, err := wch.Wallet.ImportPrivateKey(defaultAddressScope, wif, nil, true)
+	addr, err := wch.ImportKey(defaultAddressScope, wif, nil)
+	wch.Lock()
+	if waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+		log.Fatalf("the key is already in the wallet")
+	}
 	if err != nil {
 		log.Fatalf("importation failed: %v", err)
 	}
-	log.Printf("address imported: %v", addr)
-	wg.Wait()
+	fmt.Printf("Address imported: %v\n", addr)
+
+	if opts.Label != "" {
+		if err := wch.SetAddressLabel(addr, opts.Label); err != nil {
+			log.Fatalf("unable to label address: %v", err)
+		}
+	}
+
+	if opts.RescanFrom < 0 {
+		fmt.Println("Run 'rescan --from-height 0' to fetch the history of the key.")
+		return
+	}
+
+	fmt.Printf("Fetching history from height %d...\n", opts.RescanFrom)
+	stats, err := wch.ScanAddresses([]chainutil.Address{addr}, opts.RescanFrom)
+	if err != nil {
+		log.Fatalf("unable to fetch history: %v", err)
+	}
+	fmt.Printf("%d transactions found, balance %v\n", stats.Transactions, stats.Other.Funds)
 }
 
-func (wch *WalletCliHandler) ShowXpub(branch uint32, withPrivateData bool, printAddress bool) {
+// ShowXpub prints the extended keys of the account of the address type, in
+// the SLIP-132 format of its scope, and the address derived at branch.
+func (wch *WalletCliHandler) ShowXpub(branch uint32, withPrivateData bool, printAddress bool, addrType string) {
+	kind, ok := deriveTypes[addrType]
+	if !ok {
+		log.Fatalf("unsupported address type %q", addrType)
+	}
+	format := ScopeKeyFormat(kind.scope, wch.network)
 
 	if withPrivateData {
 		privPass := ReadPassword("Enter the private password to unlock the wallet: ", false)
@@ -139,7 +260,7 @@ func (wch *WalletCliHandler) ShowXpub(branch uint32, withPrivateData bool, print
 		defer wch.Lock()
 	}
 
-	props, err := wch.AccountProperties(defaultAddressScope, wch.cfg.AccountID)
+	props, err := wch.AccountProperties(kind.scope, wch.cfg.AccountID)
 	if err != nil {
 		log.Fatalf("Failed to get account props: %v", err)
 	}
@@ -154,18 +275,23 @@ func (wch *WalletCliHandler) ShowXpub(branch uint32, withPrivateData bool, print
 		log.Fatalf("Failed deriving branch: %v", err)
 	}
 
-	xpub := branchKey.String()
+	xpub, err := FormatExtendedKey(branchKey, format)
+	if err != nil {
+		log.Fatalf("Failed encoding xpub: %v", err)
+	}
 
 	var xpriv string
 	if withPrivateData {
-		xpriv = props.AccountPrivKey.String()
+		if xpriv, err = FormatExtendedKey(props.AccountPrivKey, format); err != nil {
+			log.Fatalf("Failed encoding xpriv: %v", err)
+		}
 	}
 
 	if branch == 0 {
 		branch = uint32(time.Since(wch.ChainParams().GenesisBlock.Header.Timestamp).Minutes()) // elapsed minutes
 	}
 
-	address, priv, err := DeriveKeysFromXpub(wch.ChainParams(), xpriv, xpub, branch)
+	address, priv, err := DeriveKeysFromXpub(wch.ChainParams(), xpriv, xpub, branch, kind.addrType)
 	if err != nil {
 		log.Fatalf("Failed deriving add/wif: %v", err)
 	}
@@ -204,8 +330,8 @@ func (wch *WalletCliHandler) Dashboard() {
 	log.Printf("Address type: %s", StrAddrType(waddrmgr.ScopeAddrMap[defaultAddressScope].ExternalAddrType))
 	log.Printf("Address path: %s", defaultAddressScope.String())
 
-	wch.ListAddresses()
-	wch.ShowXpub(1, false, false)
+	wch.ListAddresses(ListAddressesOptions{})
+	wch.ShowXpub(1, false, false, "p2pkh")
 	wch.Balance()
 
 	printAllTransactionHistory(wch.Wallet, -1)
@@ -255,6 +381,9 @@ func (wch *WalletCliHandler) Balance() {
 		log.Fatalf("unable to fetch balance: %v\n", err)
 	}
 	log.Printf("balance: %f", balance.Total.ToFLC())
+	wch.printWatchOnlyBalance()
+	wch.printWatchOnlyAccounts()
+	wch.printMultisigBalances()
 }
 
 func (wch *WalletCliHandler) currentState() (int32, string) {
@@ -318,7 +447,30 @@ func (wch *WalletCliHandler) Sync() {
 
 	log.Println("Syncing...")
 	wg.Wait()
+	wch.refreshWatchOnly()
+	wch.ListAccounts()
+	wch.warnGapLimit()
+}
+
+// Rescan drops the wallet history from a height on and fetches it again for
+// every known address. A negative height rescans from the wallet birthday.
+func (wch *WalletCliHandler) Rescan(fromHeight int32) {
+	if fromHeight < 0 {
+		log.Println("Rescanning from the wallet birthday...")
+	} else {
+		log.Printf("Rescanning from height %d...", fromHeight)
+	}
+
+	stats, err := wch.RescanHistory(fromHeight, printScanProgress)
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("unable to rescan: %v", err)
+	}
+
+	log.Printf("Rescan complete, %d transactions found", stats.Transactions)
+	wch.refreshWatchOnly()
 	wch.ListAccounts()
+	wch.warnGapLimit()
 }
 
 func (wch *WalletCliHandler) Transfer(password string, strAddress string, inAmount float64) {
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	. "github.com/flokiorg/fcli/utils"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// Info prints what is known about the wallet in use, to help diagnose it
+// without going through several commands.
+func (wch *WalletCliHandler) Info() {
+	path := wch.WalletFile()
+	stat, err := os.Stat(path)
+	if err != nil {
+		log.Fatalf("unable to read wallet file: %v", err)
+	}
+
+	birthday, birthdayBlock, err := wch.Birthday()
+	if err != nil {
+		log.Fatalf("unable to read wallet birthday: %v", err)
+	}
+
+	accounts, err := wch.ScopeAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch accounts: %v", err)
+	}
+
+	verified, err := wch.BackupVerified()
+	if err != nil {
+		log.Fatalf("unable to read backup status: %v", err)
+	}
+
+	height, hash := wch.currentState()
+
+	fmt.Printf("Wallet:                %s\n", wch.walletName())
+	fmt.Printf("Network:               %s\n", wch.ChainParams().Name)
+	fmt.Printf("File:                  %s (%d bytes)\n", path, stat.Size())
+	if birthdayBlock != nil {
+		fmt.Printf("Birthday:              %s (block %d)\n", birthday.UTC().Format(time.DateOnly), birthdayBlock.Height)
+	} else {
+		fmt.Printf("Birthday:              %s\n", birthday.UTC().Format(time.DateOnly))
+	}
+	fmt.Printf("Synced height:         %d\n", height)
+	fmt.Printf("Synced hash:           %s\n", hash)
+	fmt.Printf("Watch-only:            %t\n", wch.Manager.WatchOnly())
+	fmt.Printf("Public data encrypted: %t\n", wch.PublicPassword() != defaultPublicPassword)
+	fmt.Printf("Backup verified:       %t\n", verified)
+	fmt.Printf("Gap limit:             %d\n", wch.GapLimit())
+
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if a.KeyScope != b.KeyScope {
+			return a.KeyScope.Purpose < b.KeyScope.Purpose
+		}
+		return a.AccountNumber < b.AccountNumber
+	})
+
+	fmt.Println("Scopes and accounts:")
+	var scope waddrmgr.KeyScope
+	for _, acc := range accounts {
+		// The imported account of each scope only matters once used.
+		if acc.AccountNumber == waddrmgr.ImportedAddrAccount && acc.ImportedKeyCount == 0 {
+			continue
+		}
+		if acc.KeyScope != scope {
+			scope = acc.KeyScope
+			fmt.Printf(" %s (%s)\n", scope, StrAddrType(waddrmgr.ScopeAddrMap[scope].ExternalAddrType))
+		}
+		fmt.Printf("   - %d %q: %d receive, %d change, %d imported addresses\n",
+			acc.AccountNumber, acc.AccountName, acc.ExternalKeyCount, acc.InternalKeyCount, acc.ImportedKeyCount)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	walletmgr "github.com/flokiorg/fcli/wallet"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+)
+
+// SignMessage prints the signature of a message by a wallet address, proving
+// the wallet owns it.
+func (wch *WalletCliHandler) SignMessage(strAddress, message, privPassFile string) {
+	addr := wch.decodeAddress(strAddress)
+
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", privPassFile, privatePassEnv, false)
+	if err := wch.Unlock(privPass, nil); err != nil {
+		log.Fatalf("Failed to unlock wallet: %v", err)
+	}
+	defer wch.Lock()
+
+	signature, err := wch.WalletService.SignMessage(addr, message)
+	if err != nil {
+		log.Fatalf("unable to sign message: %v", err)
+	}
+	fmt.Println(signature)
+}
+
+// VerifyMessage checks the signature of a message by an address, it needs no
+// wallet.
+func (wch *WalletCliHandler) VerifyMessage(strAddress, signature, message string) {
+	addr := wch.decodeAddress(strAddress)
+
+	err := walletmgr.VerifyMessage(addr, signature, message)
+	switch {
+	case errors.Is(err, walletmgr.ErrInvalidSignature):
+		log.Fatalf("invalid signature: %v", err)
+	case err != nil:
+		log.Fatalf("unable to verify signature: %v", err)
+	}
+	fmt.Println("Signature verified, the message was signed by", addr)
+}
+
+func (wch *WalletCliHandler) decodeAddress(strAddress string) chainutil.Address {
+	addr, err := chainutil.DecodeAddress(strAddress, wch.network)
+	if err != nil {
+		log.Fatalf("invalid address: %v", err)
+	}
+	if !addr.IsForNet(wch.network) {
+		log.Fatalf("the address is not for %s", wch.network.Name)
+	}
+	return addr
+}
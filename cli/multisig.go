@@ -0,0 +1,292 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	walletmgr "github.com/flokiorg/fcli/wallet"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/chainutil/psbt"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// psbtMagic starts the binary encoding of PSBTs, base64 is assumed otherwise.
+var psbtMagic = []byte("psbt\xff")
+
+// multisigKey returns the extended public key the wallet shares with its
+// cosigners, creating it on first use.
+func (wch *WalletCliHandler) multisigKey(privPassFile string) *hdkeychain.ExtendedKey {
+	xpub, err := wch.MultisigXpub()
+	if err == nil {
+		return xpub
+	}
+	if !errors.Is(err, walletmgr.ErrNoMultisigKey) {
+		log.Fatalf("unable to fetch multisig key: %v", err)
+	}
+
+	fmt.Println("Creating the multisig key of the wallet.")
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", privPassFile, privatePassEnv, false)
+	if err := wch.Unlock(privPass, nil); err != nil {
+		log.Fatalf("Failed to unlock wallet: %v", err)
+	}
+	defer wch.Lock()
+
+	if xpub, err = wch.CreateMultisigKey(); err != nil {
+		log.Fatalf("unable to create multisig key: %v", err)
+	}
+	return xpub
+}
+
+// ShowMultisigXpub prints the extended public key to give to the cosigners.
+func (wch *WalletCliHandler) ShowMultisigXpub(privPassFile string) {
+	fmt.Println(wch.multisigKey(privPassFile))
+}
+
+// CreateMultisig adds an m-of-n account shared with the cosigners and prints
+// its first receive address.
+func (wch *WalletCliHandler) CreateMultisig(name string, threshold int, scriptType string, cosigners []string, privPassFile string) {
+	if !walletNamePattern.MatchString(name) {
+		log.Fatalf("invalid account name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+
+	wch.multisigKey(privPassFile)
+
+	account, err := wch.CreateMultisigAccount(name, threshold, scriptType, cosigners)
+	if err != nil {
+		log.Fatalf("unable to create multisig account: %v", err)
+	}
+
+	addr, err := wch.WalletService.NewMultisigAddress(name, waddrmgr.ExternalBranch)
+	if err != nil {
+		log.Fatalf("unable to derive address: %v", err)
+	}
+
+	fmt.Printf("Multisig account %s created: %d-of-%d %s\n", account.Name, account.Threshold, len(account.Keys), account.Type)
+	for _, key := range account.Keys {
+		mark := ""
+		if key == account.OwnKey {
+			mark = " (this wallet)"
+		}
+		fmt.Printf("  %s%s\n", key, mark)
+	}
+	fmt.Printf("First address: %s\n", addr)
+	fmt.Println("Every cosigner must create the account with the same keys and threshold to track and sign it.")
+}
+
+// ListMultisig prints the multisig accounts with their balance as of the last
+// sync.
+func (wch *WalletCliHandler) ListMultisig() {
+	accounts, err := wch.MultisigAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch multisig accounts: %v", err)
+	}
+	if len(accounts) == 0 {
+		fmt.Println("No multisig account, create one with 'multisig create'.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPOLICY\tTYPE\tADDRESSES\tBALANCE")
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%s\t%d-of-%d\t%s\t%d\t%f\n", account.Name, account.Threshold, len(account.Keys),
+			account.Type, account.ExternalCount+account.InternalCount, account.Balance().ToFLC())
+	}
+	w.Flush()
+}
+
+// NewMultisigAddress prints the next receive address of a multisig account.
+func (wch *WalletCliHandler) NewMultisigAddress(name string) {
+	addr, err := wch.WalletService.NewMultisigAddress(name, waddrmgr.ExternalBranch)
+	if err != nil {
+		log.Fatalf("unable to derive address: %v", err)
+	}
+	fmt.Println(addr)
+}
+
+// printMultisigBalances prints the balance of each multisig account, if any.
+func (wch *WalletCliHandler) printMultisigBalances() {
+	accounts, err := wch.MultisigAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch multisig accounts: %v", err)
+	}
+	for _, account := range accounts {
+		log.Printf("multisig %s balance: %f (%d-of-%d)", account.Name, account.Balance().ToFLC(), account.Threshold, len(account.Keys))
+	}
+}
+
+// MultisigTransactions prints the history of a multisig account, most recent
+// first.
+func (wch *WalletCliHandler) MultisigTransactions(name string, limit int) {
+	account, err := wch.MultisigAccount(name)
+	if err != nil {
+		log.Fatalf("unable to fetch multisig account %s: %v", name, err)
+	}
+	labels, err := wch.AddressLabels()
+	if err != nil {
+		log.Fatalf("unable to fetch labels: %v", err)
+	}
+
+	// A transaction touching several addresses of the account is shown
+	// once with its net amount.
+	var rows []historyRow
+	seen := make(map[string]int)
+	for _, ma := range account.Addresses {
+		for _, tx := range ma.History {
+			if i, ok := seen[tx.TxID]; ok {
+				rows[i].tx.Amount += tx.Amount
+				continue
+			}
+			seen[tx.TxID] = len(rows)
+			rows = append(rows, historyRow{tx, ma.Address})
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Printf("No transactions found for multisig account %s.\n", name)
+		return
+	}
+
+	fmt.Printf("Multisig %s transactions\n", name)
+	printHistoryRows(rows, labels, limit)
+}
+
+// MultisigSend writes a PSBT paying the recipients from a multisig account,
+// to be signed by the cosigners. The fee rate is in loki per virtual byte.
+func (wch *WalletCliHandler) MultisigSend(name string, strAddresses []string, inAmounts []float64, feeRate int64, out string) {
+	if len(strAddresses) == 0 || len(strAddresses) != len(inAmounts) {
+		log.Fatalf("give as many --to as --amount (%d != %d)", len(strAddresses), len(inAmounts))
+	}
+
+	var outputs []*wire.TxOut
+	for i, strAddress := range strAddresses {
+		addr, err := chainutil.DecodeAddress(strAddress, wch.network)
+		if err != nil || !addr.IsForNet(wch.network) {
+			log.Fatalf("invalid address %s", strAddress)
+		}
+		amount, err := chainutil.NewAmount(inAmounts[i])
+		if err != nil || amount <= 0 {
+			log.Fatalf("invalid amount %v", inAmounts[i])
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			log.Fatalf("unable to pay %s: %v", addr, err)
+		}
+		outputs = append(outputs, wire.NewTxOut(int64(amount), script))
+	}
+
+	if _, err := os.Stat(out); err == nil {
+		log.Fatalf("%s already exists", out)
+	}
+
+	packet, err := wch.FundMultisig(name, outputs, chainutil.Amount(feeRate))
+	if err != nil {
+		log.Fatalf("unable to build transaction: %v", err)
+	}
+	fee, err := packet.GetTxFee()
+	if err != nil {
+		log.Fatalf("unable to compute fee: %v", err)
+	}
+
+	writePSBT(out, packet)
+	fmt.Printf("PSBT written to %s: %d inputs, %d outputs, fee %v\n", out, len(packet.Inputs), len(packet.Outputs), fee)
+	fmt.Println("Each cosigner signs it with 'multisig sign', then 'multisig combine' and 'multisig finalize'.")
+}
+
+// SignPSBT adds the signatures of the wallet to a PSBT, written to out.
+func (wch *WalletCliHandler) SignPSBT(path, out, privPassFile string) {
+	packet := readPSBT(path)
+
+	privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", privPassFile, privatePassEnv, false)
+	if err := wch.Unlock(privPass, nil); err != nil {
+		log.Fatalf("Failed to unlock wallet: %v", err)
+	}
+	signed, err := wch.SignMultisigPSBT(packet)
+	wch.Lock()
+	if err != nil {
+		log.Fatalf("unable to sign: %v", err)
+	}
+	if signed == 0 {
+		log.Fatal("no input of the PSBT could be signed by this wallet")
+	}
+
+	writePSBT(out, packet)
+	fmt.Printf("%d of %d inputs signed, PSBT written to %s\n", signed, len(packet.Inputs), out)
+}
+
+// CombinePSBTs merges the signatures of several copies of a PSBT.
+func (wch *WalletCliHandler) CombinePSBTs(paths []string, out string) {
+	var packets []*psbt.Packet
+	for _, path := range paths {
+		packets = append(packets, readPSBT(path))
+	}
+
+	combined, err := walletmgr.CombinePSBTs(packets)
+	if err != nil {
+		log.Fatalf("unable to combine: %v", err)
+	}
+
+	writePSBT(out, combined)
+	fmt.Printf("%d PSBTs combined into %s\n", len(paths), out)
+}
+
+// FinalizePSBT completes a signed PSBT and broadcasts the transaction, or
+// prints it when broadcast is not set.
+func (wch *WalletCliHandler) FinalizePSBT(path string, broadcast bool) {
+	tx, err := walletmgr.FinalizePSBT(readPSBT(path))
+	if err != nil {
+		log.Fatalf("unable to finalize: %v", err)
+	}
+
+	if !broadcast {
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			log.Fatalf("unable to encode transaction: %v", err)
+		}
+		fmt.Println(hex.EncodeToString(buf.Bytes()))
+		return
+	}
+
+	txid, err := wch.BroadcastTransaction(tx)
+	if err != nil {
+		log.Fatalf("unable to broadcast: %v", err)
+	}
+	fmt.Println(txid)
+}
+
+func readPSBT(path string) *psbt.Packet {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("unable to read PSBT: %v", err)
+	}
+
+	b64 := !bytes.HasPrefix(data, psbtMagic)
+	if b64 {
+		data = bytes.TrimSpace(data)
+	}
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(data), b64)
+	if err != nil {
+		log.Fatalf("invalid PSBT %s: %v", path, err)
+	}
+	return packet
+}
+
+func writePSBT(path string, packet *psbt.Packet) {
+	encoded, err := packet.B64Encode()
+	if err != nil {
+		log.Fatalf("unable to encode PSBT: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+		log.Fatalf("unable to write PSBT: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+type NewAddressesOptions struct {
+	// Count is the number of receive addresses to derive.
+	Count uint32
+
+	// Out is the file the addresses are written to, as CSV or JSON after
+	// its extension. They are printed when empty.
+	Out string
+
+	// LabelTemplate labels every address, "{index}" is replaced by the
+	// address index, "{n}" by its position in the batch starting at 1.
+	LabelTemplate string
+}
+
+// batchAddress is an address of a batch as written to the export file.
+type batchAddress struct {
+	Index   uint32 `json:"index"`
+	Path    string `json:"path"`
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+}
+
+// GenerateNewAddresses derives a batch of receive addresses, labels them after
+// the template and writes them out. It warns when the batch leaves more unused
+// addresses in a row than a recovery scans.
+func (wch *WalletCliHandler) GenerateNewAddresses(opts NewAddressesOptions) {
+	format := ""
+	if opts.Out != "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(opts.Out), "."))
+		if format != "csv" && format != "json" {
+			log.Fatalf("unsupported output format %q, use a .csv or .json file", filepath.Ext(opts.Out))
+		}
+	}
+
+	lastUsed, err := wch.lastUsedReceiveIndex()
+	if err != nil {
+		log.Fatalf("unable to fetch addresses: %v", err)
+	}
+
+	addrs, err := wch.CreateNewAddresses(opts.Count)
+	if err != nil {
+		log.Fatalf("failed creating addresses: %v", err)
+	}
+
+	batch := make([]batchAddress, 0, len(addrs))
+	for i, addr := range addrs {
+		ba := batchAddress{Index: addr.Index, Path: addr.Path, Address: addr.Address.EncodeAddress()}
+		if opts.LabelTemplate != "" {
+			ba.Label = strings.NewReplacer(
+				"{index}", strconv.FormatUint(uint64(addr.Index), 10),
+				"{n}", strconv.Itoa(i+1),
+			).Replace(opts.LabelTemplate)
+			if err := wch.SetAddressLabel(addr.Address, ba.Label); err != nil {
+				log.Fatalf("unable to label %s: %v", ba.Address, err)
+			}
+		}
+		batch = append(batch, ba)
+	}
+
+	switch format {
+	case "csv":
+		writeBatchCSV(opts.Out, batch)
+		fmt.Printf("%d addresses written to %s\n", len(batch), opts.Out)
+	case "json":
+		writeBatchJSON(opts.Out, batch)
+		fmt.Printf("%d addresses written to %s\n", len(batch), opts.Out)
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, ba := range batch {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", ba.Index, ba.Path, ba.Address, ba.Label)
+		}
+		w.Flush()
+	}
+
+	// A recovery stops after GapLimit unused addresses in a row, payments to
+	// addresses past that run would be missed.
+	if len(addrs) == 0 {
+		return
+	}
+	gap := int64(addrs[len(addrs)-1].Index) - lastUsed
+	if limit := wch.GapLimit(); gap > int64(limit) {
+		fmt.Printf("WARNING: %d unused receive addresses in a row, more than the gap limit of %d.\n", gap, limit)
+		fmt.Println("A recovery would miss payments to the last ones unless earlier ones are used first, raise the limit with --gap-limit on restore and sync.")
+	}
+}
+
+// lastUsedReceiveIndex returns the index of the last used receive address of
+// the account, -1 when none was used.
+func (wch *WalletCliHandler) lastUsedReceiveIndex() (int64, error) {
+	addrs, err := wch.AccountAddressDetails(wch.cfg.AccountID)
+	if err != nil {
+		return 0, err
+	}
+
+	last := int64(-1)
+	for _, addr := range addrs {
+		if addr.Scope != defaultAddressScope || addr.Imported || addr.Branch != waddrmgr.ExternalBranch || !addr.Used {
+			continue
+		}
+		last = max(last, int64(addr.Index))
+	}
+	return last, nil
+}
+
+func writeBatchCSV(path string, batch []batchAddress) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatalf("unable to write %s: %v", path, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"index", "path", "address", "label"})
+	for _, ba := range batch {
+		w.Write([]string{strconv.FormatUint(uint64(ba.Index), 10), ba.Path, ba.Address, ba.Label})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("unable to write %s: %v", path, err)
+	}
+}
+
+func writeBatchJSON(path string, batch []batchAddress) {
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		log.Fatalf("unable to encode addresses: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		log.Fatalf("unable to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	walletmgr "github.com/flokiorg/fcli/wallet"
+)
+
+// progressBarWidth is the number of cells of the scan progress bar.
+var progressBarWidth = 30
+
+// Recover discovers the addresses used by the wallet and restores their
+// history, showing the scan progress of both branches.
+func (wch *WalletCliHandler) Recover() {
+	fmt.Printf("Recovering wallet history (gap limit %d)...\n", wch.GapLimit())
+
+	stats, err := wch.WalletService.Recover(printScanProgress)
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("unable to recover wallet: %v", err)
+	}
+
+	balance, err := wch.CalculateAccountBalances(wch.cfg.AccountID, 0)
+	if err != nil {
+		log.Fatalf("unable to fetch balance: %v", err)
+	}
+
+	fmt.Println("Recovery complete:")
+	fmt.Printf("  Receive addresses: %d used out of %d scanned\n", stats.External.Used, stats.External.Scanned)
+	fmt.Printf("  Change addresses:  %d used out of %d scanned\n", stats.Internal.Used, stats.Internal.Scanned)
+	fmt.Printf("  Transactions:      %d\n", stats.Transactions)
+	fmt.Printf("  Balance:           %v\n", balance.Total)
+
+	wch.warnGapLimit()
+}
+
+// printScanProgress redraws the progress line of an address scan.
+func printScanProgress(stats *walletmgr.ScanStats) {
+	scanned := stats.External.Scanned + stats.Internal.Scanned
+	total := stats.External.Total + stats.Internal.Total
+	filled := progressBarWidth
+	if total > 0 {
+		filled = int(uint64(scanned) * uint64(progressBarWidth) / uint64(total))
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	fmt.Printf("\r[%s] %3d%% | receive %d/%d, %d used, %v | change %d/%d, %d used, %v",
+		bar, filled*100/progressBarWidth,
+		stats.External.Scanned, stats.External.Total, stats.External.Used, stats.External.Funds,
+		stats.Internal.Scanned, stats.Internal.Total, stats.Internal.Used, stats.Internal.Funds)
+	if stats.Other.Scanned > 0 {
+		fmt.Printf(" | other %d, %d used", stats.Other.Scanned, stats.Other.Used)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/flokiorg/fcli/slip39"
+	. "github.com/flokiorg/fcli/utils"
+)
+
+// shareWordsPerLine is the number of share words printed per line.
+const shareWordsPerLine = 5
+
+// SplitSeed splits the seed of the opened wallet into SLIP-39 shares, any
+// threshold of which restore the wallet. The wallet does not keep its seed,
+// so its mnemonic is asked for and checked first.
+func (wch *WalletCliHandler) SplitSeed(threshold, count int, seedPassphrase, sharePassphrase bool) {
+	seed := wch.readWalletSeed(seedPassphrase)
+
+	var passphrase []byte
+	if sharePassphrase {
+		passphrase = ReadPassword("Enter the passphrase protecting the shares: ", true)
+	}
+	printShares(threshold, count, seed, passphrase)
+}
+
+// printShares splits a seed into SLIP-39 shares and prints them.
+func printShares(threshold, count int, seed, passphrase []byte) {
+	shares, err := slip39.Split(threshold, count, seed, passphrase)
+	if err != nil {
+		log.Fatalf("unable to split seed: %v", err)
+	}
+
+	fmt.Printf("\nAny %d of the following %d shares restore the wallet with `restore --shares`.\n", threshold, count)
+	for i, share := range shares {
+		words := strings.Fields(share)
+		fmt.Printf("\n========== Share %d of %d ==========\n", i+1, count)
+		for j := 0; j < len(words); j += shareWordsPerLine {
+			fmt.Printf("  %s\n", strings.Join(words[j:min(j+shareWordsPerLine, len(words))], " "))
+		}
+	}
+	fmt.Println("\n==================================")
+
+	fmt.Println("\nGive each share to a different holder, a single share reveals nothing about the seed.")
+	if len(passphrase) > 0 {
+		fmt.Println("The share passphrase is required along with the shares, store it separately.")
+	}
+}
+
+// seedFromShares reads SLIP-39 shares, one per line, and recovers the seed
+// they hold.
+func seedFromShares(sharePassphrase bool) []byte {
+	fmt.Println("Enter your shares, one per line. Leave the line empty when done.")
+
+	reader := bufio.NewReader(os.Stdin)
+	var shares []string
+	for {
+		fmt.Printf("Share %d: ", len(shares)+1)
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			break
+		}
+
+		if _, err := slip39.Threshold(line); err != nil {
+			fmt.Printf("Invalid share: %v\n", err)
+			continue
+		}
+		shares = append(shares, line)
+	}
+
+	var passphrase []byte
+	if sharePassphrase {
+		passphrase = ReadPassword("Enter the passphrase of the shares: ", false)
+	}
+
+	seed, err := slip39.Combine(shares, passphrase)
+	if err != nil {
+		log.Fatalf("unable to recover seed from shares: %v", err)
+	}
+	return seed
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/flokiorg/fcli/utils"
+	walletmgr "github.com/flokiorg/fcli/wallet"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/walletd/wallet"
+)
+
+var (
+	// defaultWalletName names the wallet kept at the root of the wallet
+	// directory, as used before named wallets existed.
+	defaultWalletName = "default"
+
+	// walletsDirName is the subdirectory of the wallet directory holding
+	// the named wallets, one directory each.
+	walletsDirName = "wallets"
+
+	// defaultWalletFile holds the name of the wallet used when --wallet is
+	// not given.
+	defaultWalletFile = "default-wallet"
+
+	// trashDirName is the subdirectory of the wallet directory receiving
+	// deleted wallets.
+	trashDirName = "trash"
+
+	walletNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// WalletPath returns the directory of the named wallet within the wallet
+// directory. An empty name selects the default wallet.
+func WalletPath(walletDir, name string) (string, error) {
+	if name == "" {
+		name = defaultWallet(walletDir)
+	}
+	if name == defaultWalletName {
+		return walletDir, nil
+	}
+	if !walletNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid wallet name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return filepath.Join(walletDir, walletsDirName, name), nil
+}
+
+// defaultWallet returns the name of the selected default wallet.
+func defaultWallet(walletDir string) string {
+	name, err := os.ReadFile(filepath.Join(walletDir, defaultWalletFile))
+	if err != nil || strings.TrimSpace(string(name)) == "" {
+		return defaultWalletName
+	}
+	return strings.TrimSpace(string(name))
+}
+
+// walletNames returns the names of the wallets found in the wallet directory.
+func walletNames(walletDir string) ([]string, error) {
+	var names []string
+	if walletFileExists(walletDir) {
+		names = append(names, defaultWalletName)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(walletDir, walletsDirName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && walletFileExists(filepath.Join(walletDir, walletsDirName, entry.Name())) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func walletFileExists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, wallet.WalletDBName))
+	return err == nil
+}
+
+// ListWallets prints the wallets of the wallet directory with their network
+// and birthday, the default one being marked.
+func (wch *WalletCliHandler) ListWallets() {
+	names, err := walletNames(wch.cfg.WalletDir)
+	if err != nil {
+		log.Fatalf("unable to list wallets: %v", err)
+	}
+	if len(names) == 0 {
+		fmt.Printf("No wallet found in %s\n", wch.cfg.WalletDir)
+		return
+	}
+
+	current := defaultWallet(wch.cfg.WalletDir)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tNETWORK\tBIRTHDAY\tPATH")
+	for _, name := range names {
+		dir, _ := WalletPath(wch.cfg.WalletDir, name)
+
+		network, birthday := "unknown", "unknown"
+		summary, err := walletmgr.ReadWalletSummary(dir, wch.cfg.DBTimeout)
+		switch {
+		case err != nil:
+			network, birthday = "unreadable", "-"
+		default:
+			if summary.Network != "" {
+				network = summary.Network
+			}
+			if !summary.Birthday.IsZero() {
+				birthday = summary.Birthday.UTC().Format(time.DateOnly)
+			}
+		}
+
+		mark := ""
+		if name == current {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mark, name, network, birthday, filepath.Join(dir, wallet.WalletDBName))
+	}
+	w.Flush()
+}
+
+// SetDefaultWallet selects the wallet used when --wallet is not given.
+func (wch *WalletCliHandler) SetDefaultWallet(name string) {
+	dir, err := WalletPath(wch.cfg.WalletDir, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !walletFileExists(dir) {
+		log.Fatalf("wallet %q not found", name)
+	}
+
+	path := filepath.Join(wch.cfg.WalletDir, defaultWalletFile)
+	if name == defaultWalletName {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("unable to reset default wallet: %v", err)
+		}
+	} else if err := os.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		log.Fatalf("unable to set default wallet: %v", err)
+	}
+
+	fmt.Printf("Default wallet set to %s\n", name)
+}
+
+// walletName returns the name of the wallet in use.
+func (wch *WalletCliHandler) walletName() string {
+	if wch.cfg.Wallet != "" {
+		return wch.cfg.Wallet
+	}
+	return defaultWallet(wch.cfg.WalletDir)
+}
+
+// DeleteWallet moves the wallet in use to a timestamped directory of the
+// trash, once the user typed its name. The private passphrase is checked
+// first when requirePrivPass is set, read from privPassFile if given.
+func (wch *WalletCliHandler) DeleteWallet(requirePrivPass bool, privPassFile string) {
+	name := wch.walletName()
+
+	// Every scope counts, keys imported in any of them and watch-only
+	// accounts included, along with the funds tracked apart from the wallet.
+	var balance chainutil.Amount
+	var height int32
+	for _, manager := range wch.Manager.ActiveScopedKeyManagers() {
+		accounts, err := wch.Accounts(manager.Scope())
+		if err != nil {
+			log.Fatalf("unable to fetch accounts: %v", err)
+		}
+		for _, acc := range accounts.Accounts {
+			balance += acc.TotalBalance
+		}
+		height = accounts.CurrentBlockHeight
+	}
+
+	watched, err := wch.WatchedAddresses()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only addresses: %v", err)
+	}
+	var watchedBalance chainutil.Amount
+	for _, wad := range watched {
+		watchedBalance += wad.Balance
+	}
+
+	multisig, err := wch.MultisigAccounts()
+	if err != nil {
+		log.Fatalf("unable to fetch multisig accounts: %v", err)
+	}
+	var multisigBalance chainutil.Amount
+	for _, account := range multisig {
+		multisigBalance += account.Balance()
+	}
+
+	verified, err := wch.BackupVerified()
+	if err != nil {
+		log.Fatalf("unable to read backup status: %v", err)
+	}
+
+	fmt.Printf("Wallet:          %s\n", name)
+	fmt.Printf("Path:            %s\n", wch.WalletFile())
+	fmt.Printf("Balance:         %v (as of height %d)\n", balance, height)
+	fmt.Printf("Watch-only:      %v (watched addresses, as of their last refresh)\n", watchedBalance)
+	fmt.Printf("Multisig:        %v (as of the last refresh)\n", multisigBalance)
+	fmt.Printf("Backup verified: %t\n", verified)
+	fmt.Println()
+
+	if balance > 0 || watchedBalance > 0 || multisigBalance > 0 {
+		fmt.Println("WARNING: the wallet still holds funds.")
+	}
+	if !verified {
+		fmt.Println("WARNING: the seed backup was never verified, run verify-backup first.")
+	}
+
+	if requirePrivPass || privPassFile != "" {
+		privPass := readSecret("Enter the private passphrase to unlock the wallet: ", "--privpass-file", privPassFile, privatePassEnv, false)
+		if err := wch.Unlock(privPass, nil); err != nil {
+			log.Fatalf("Failed to unlock wallet: %v", err)
+		}
+		wch.Lock()
+	}
+
+	if ReadInput(fmt.Sprintf("Type the wallet name (%s) to confirm the deletion: ", name)) != name {
+		log.Fatal("deletion cancelled")
+	}
+
+	trashDir := filepath.Join(wch.cfg.WalletDir, trashDirName, name+"-"+time.Now().Format("20060102-150405"))
+	trashed, err := wch.TrashWallet(trashDir)
+	if err != nil {
+		log.Fatalf("unable to delete wallet: %v", err)
+	}
+
+	// The directory of a named wallet is left empty.
+	if wch.cfg.WalletPath != wch.cfg.WalletDir {
+		os.Remove(wch.cfg.WalletPath)
+	}
+	if defaultWallet(wch.cfg.WalletDir) == name && name != defaultWalletName {
+		os.Remove(filepath.Join(wch.cfg.WalletDir, defaultWalletFile))
+	}
+
+	fmt.Printf("Wallet %s deleted, its database was moved to %s\n", name, trashed)
+}
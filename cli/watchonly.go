@@ -0,0 +1,145 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	walletmgr "github.com/flokiorg/fcli/wallet"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+)
+
+// ImportAddress starts tracking an address without its key. Its history is
+// kept from rescanFrom on, and fetched right away when rescan is set.
+func (wch *WalletCliHandler) ImportAddress(strAddress, label string, rescanFrom int32, rescan bool) {
+	addr, err := chainutil.DecodeAddress(strAddress, wch.ChainParams())
+	if err != nil {
+		log.Fatalf("invalid address: %v", err)
+	}
+	if !addr.IsForNet(wch.ChainParams()) {
+		log.Fatalf("the address is not for %s", wch.ChainParams().Name)
+	}
+
+	err = wch.AddWatchedAddress(addr, rescanFrom)
+	switch {
+	case errors.Is(err, walletmgr.ErrAddressOwned):
+		log.Fatalf("%s belongs to the wallet, it is already tracked", addr)
+	case err != nil:
+		log.Fatalf("unable to import address: %v", err)
+	}
+	fmt.Printf("Watching %s\n", addr)
+
+	if label != "" {
+		if err := wch.SetAddressLabel(addr, label); err != nil {
+			log.Fatalf("unable to label address: %v", err)
+		}
+	}
+
+	if !rescan {
+		fmt.Println("Its history will be fetched on the next sync.")
+		return
+	}
+
+	fmt.Printf("Fetching history from height %d...\n", rescanFrom)
+	if err := wch.RefreshWatchedAddresses(); err != nil {
+		log.Fatalf("unable to fetch history: %v", err)
+	}
+	wch.printWatchOnlyBalance()
+}
+
+// refreshWatchOnly updates the watch-only addresses and the multisig accounts
+// after a sync, a failure only leaves their balance stale.
+func (wch *WalletCliHandler) refreshWatchOnly() {
+	if err := wch.RefreshWatchedAddresses(); err != nil {
+		log.Printf("unable to refresh watch-only addresses: %v", err)
+	}
+	if err := wch.RefreshMultisigAccounts(); err != nil {
+		log.Printf("unable to refresh multisig accounts: %v", err)
+	}
+}
+
+// printWatchOnlyBalance prints the balance of the watch-only addresses, if
+// any.
+func (wch *WalletCliHandler) printWatchOnlyBalance() {
+	watched, err := wch.WatchedAddresses()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only addresses: %v", err)
+	}
+	if len(watched) == 0 {
+		return
+	}
+
+	balance, err := wch.WatchOnlyBalance()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only balance: %v", err)
+	}
+	log.Printf("watch-only balance: %f (%d addresses)", balance.ToFLC(), len(watched))
+}
+
+// WatchOnlyTransactions prints the history of the watch-only addresses, most
+// recent first.
+func (wch *WalletCliHandler) WatchOnlyTransactions(limit int) {
+	watched, err := wch.WatchedAddresses()
+	if err != nil {
+		log.Fatalf("unable to fetch watch-only addresses: %v", err)
+	}
+	labels, err := wch.AddressLabels()
+	if err != nil {
+		log.Fatalf("unable to fetch labels: %v", err)
+	}
+
+	var rows []historyRow
+	for _, wad := range watched {
+		for _, tx := range wad.History {
+			rows = append(rows, historyRow{tx, wad.Address})
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No watch-only transactions found.")
+		return
+	}
+
+	fmt.Printf("Watch-only transactions\n-----------------------\n")
+	printHistoryRows(rows, labels, limit)
+}
+
+// historyRow is a transaction of an address tracked outside of the wallet.
+type historyRow struct {
+	tx      walletmgr.WatchedTx
+	address string
+}
+
+// printHistoryRows prints transactions, unconfirmed ones first then by
+// decreasing height.
+func printHistoryRows(rows []historyRow, labels map[string]string, limit int) {
+	height := func(r historyRow) int32 {
+		if r.tx.Height == 0 {
+			return 1<<31 - 1
+		}
+		return r.tx.Height
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return height(rows[i]) > height(rows[j])
+	})
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range rows {
+		when := "unconfirmed"
+		if r.tx.Height > 0 {
+			when = r.tx.Time.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%f\t%s\n", when, r.tx.TxID, r.address, r.tx.Amount.ToFLC(), labels[r.address])
+	}
+	w.Flush()
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type AddressBookCommand struct{}
+
+type AddressBookAddCommand struct {
+	Note    string `long:"note" description:"Free text kept with the destination"`
+	Replace bool   `long:"replace" description:"Replace the destination of the same name"`
+	Args    struct {
+		Name    string `positional-arg-name:"name" description:"Name of the destination"`
+		Address string `positional-arg-name:"address" description:"Address of the destination"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressBookAddCommand) Execute(args []string) error {
+	s.Handler.AddPayee(s.Args.Name, s.Args.Address, s.Note, s.Replace)
+	return nil
+}
+
+type AddressBookListCommand struct {
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressBookListCommand) Execute(args []string) error {
+	s.Handler.ListPayees()
+	return nil
+}
+
+type AddressBookRemoveCommand struct {
+	Args struct {
+		Name string `positional-arg-name:"name" description:"Name of the destination"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressBookRemoveCommand) Execute(args []string) error {
+	s.Handler.RemovePayee(s.Args.Name)
+	return nil
+}
+
+type AddressBookImportCommand struct {
+	Replace bool `long:"replace" description:"Replace the destinations of the same name"`
+	Args    struct {
+		File string `positional-arg-name:"file" description:"JSON file, or CSV with a .csv extension, \"-\" for standard input"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressBookImportCommand) Execute(args []string) error {
+	s.Handler.ImportPayees(s.Args.File, s.Replace)
+	return nil
+}
+
+type AddressBookExportCommand struct {
+	Args struct {
+		File string `positional-arg-name:"file" description:"JSON file, or CSV with a .csv extension, \"-\" for standard output"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressBookExportCommand) Execute(args []string) error {
+	s.Handler.ExportPayees(s.Args.File)
+	return nil
+}
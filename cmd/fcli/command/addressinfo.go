@@ -0,0 +1,23 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type AddressInfoCommand struct {
+	Args struct {
+		Address string `positional-arg-name:"address" description:"Address to inspect"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *AddressInfoCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.AddressInfo(s.Args.Address)
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 
 type TransactionInput struct {
 	Address string  `json:"address"`
+	To      string  `json:"to"` // name in the address book, instead of the address
 	Amount  float64 `json:"amount"`
 }
 
@@ -53,6 +54,12 @@ func (s *BulkTransferCommand) Execute(args []string) error {
 		s.Addresses = nil
 		s.Amounts = nil
 		for _, input := range txInputs {
+			if input.To != "" {
+				if input.Address != "" {
+					log.Fatalf("%q: give either an address or a name, not both", input.To)
+				}
+				input.Address = s.Handler.PayeeAddress(input.To)
+			}
 			s.Addresses = append(s.Addresses, input.Address)
 			s.Amounts = append(s.Amounts, input.Amount)
 		}
@@ -0,0 +1,21 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type ChangePubPassCommand struct {
+	Default bool `long:"default" description:"Revert to the well-known public passphrase (disables the encryption)"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *ChangePubPassCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.ChangePublicPassword(s.Default)
+	return nil
+}
@@ -9,10 +9,28 @@ import (
 )
 
 type CreateCommand struct {
+	PublicDefault   bool   `long:"no-pubpass" description:"Do not encrypt public data (addresses, history) with a public passphrase"`
+	SeedPassphrase  bool   `long:"bip39-passphrase" description:"Protect the seed with a BIP39 passphrase (25th word)"`
+	Language        string `long:"language" description:"Mnemonic language" default:"english" choice:"english" choice:"spanish" choice:"french" choice:"italian" choice:"japanese" choice:"korean" choice:"czech" choice:"chinese-simplified" choice:"chinese-traditional"`
+	Words           int    `long:"words" description:"Number of mnemonic words" default:"24" choice:"12" choice:"15" choice:"18" choice:"21" choice:"24"`
+	SkipVerify      bool   `long:"skip-verify" description:"Do not verify the mnemonic backup after creation"`
+	SplitThreshold  int    `long:"split-threshold" description:"Also split the seed into SLIP-39 shares, this many of which restore the wallet"`
+	SplitShares     int    `long:"split-shares" description:"Number of SLIP-39 shares to produce (at most 16)"`
+	SharePassphrase bool   `long:"share-passphrase" description:"Protect the shares with a passphrase"`
+
 	Handler *cli.WalletCliHandler
 }
 
 func (s *CreateCommand) Execute(args []string) error {
-	s.Handler.CreateWallet()
+	s.Handler.CreateWallet(cli.CreateOptions{
+		PublicDefault:   s.PublicDefault,
+		SeedPassphrase:  s.SeedPassphrase,
+		Language:        s.Language,
+		Words:           s.Words,
+		SkipVerify:      s.SkipVerify,
+		SplitThreshold:  s.SplitThreshold,
+		SplitShares:     s.SplitShares,
+		SharePassphrase: s.SharePassphrase,
+	})
 	return nil
 }
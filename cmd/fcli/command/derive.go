@@ -0,0 +1,52 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type DeriveCommand struct {
+	Key             string `short:"k" long:"key" description:"Extended public or private key to derive from, xpub, ypub, zpub or their private and testnet forms, instead of the wallet account selected by --id"`
+	Type            string `long:"type" description:"Address type, also selecting the wallet account scope (default: after the key format, p2pkh for the wallet)" choice:"p2pkh" choice:"p2sh-p2wpkh" choice:"p2wpkh" choice:"p2tr"`
+	WIF             bool   `long:"wif" description:"Also print the private keys in WIF. Use with caution."`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS"`
+	Args            struct {
+		Path string `positional-arg-name:"path" description:"BIP32 path below the key, with ranges such as 0/0-99 (default: 0-1/0-19, both branches)"`
+	} `positional-args:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *DeriveCommand) Execute(args []string) error {
+	if s.Args.Path == "" {
+		s.Args.Path = "0-1/0-19"
+	}
+	if s.Key == "" {
+		s.Handler.RequireWallet()
+	}
+	s.Handler.Derive(cli.DeriveOptions{
+		Key:             s.Key,
+		Path:            s.Args.Path,
+		Type:            s.Type,
+		WIF:             s.WIF,
+		PrivatePassFile: s.PrivatePassFile,
+	})
+	return nil
+}
+
+type ConvertKeyCommand struct {
+	To   string `long:"to" description:"Format to convert to, such as xpub, ypub, zpub, Ypub or Zpub, every format of the network when omitted"`
+	Args struct {
+		Key string `positional-arg-name:"key" description:"Extended public or private key"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *ConvertKeyCommand) Execute(args []string) error {
+	s.Handler.ConvertKey(s.Args.Key, s.To)
+	return nil
+}
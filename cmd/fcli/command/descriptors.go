@@ -0,0 +1,54 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type DescriptorsCommand struct{}
+
+type DescriptorsExportCommand struct {
+	Private         bool   `long:"private" description:"Export the private descriptors, the wallet is unlocked; watch-only accounts stay public"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *DescriptorsExportCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.ExportDescriptors(s.Private, s.PrivatePassFile)
+	return nil
+}
+
+type DescriptorsImportCommand struct {
+	File   string `short:"f" long:"file" description:"Read the descriptors from a file, one per line, \"-\" for standard input"`
+	Name   string `long:"name" description:"Name of the watch-only account, numbered when several are imported" default:"imported"`
+	Rescan bool   `long:"rescan" description:"Fetch the history of the imported scripts now"`
+	Args   struct {
+		Descriptors []string `positional-arg-name:"descriptor" description:"Output descriptors"`
+	} `positional-args:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *DescriptorsImportCommand) Execute(args []string) error {
+	if len(s.Args.Descriptors) == 0 && s.File == "" {
+		return fmt.Errorf("give descriptors as arguments or with --file")
+	}
+	if s.Rescan {
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.ImportDescriptors(s.Args.Descriptors, s.File, s.Name, s.Rescan)
+	return nil
+}
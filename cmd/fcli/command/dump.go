@@ -0,0 +1,58 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type DumpWalletCommand struct {
+	Unencrypted     bool   `long:"unencrypted" description:"Write the keys in plain text instead of encrypting the dump"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from this file"`
+	PassphraseFile  string `long:"passphrase-file" description:"Read the passphrase of the dump from this file"`
+	Args            struct {
+		File string `positional-arg-name:"file" description:"Dump file to create"`
+	} `positional-args:"yes" required:"yes"`
+	Handler *cli.WalletCliHandler
+}
+
+func (s *DumpWalletCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.DumpWallet(s.Args.File, cli.DumpOptions{
+		Plaintext:       s.Unencrypted,
+		PrivatePassFile: s.PrivatePassFile,
+		PassphraseFile:  s.PassphraseFile,
+	})
+	return nil
+}
+
+type ImportWalletCommand struct {
+	Rescan          bool   `long:"rescan" description:"Fetch the history of the imported keys from the Electrum server"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from this file"`
+	PassphraseFile  string `long:"passphrase-file" description:"Read the passphrase of the dump from this file"`
+	Args            struct {
+		File string `positional-arg-name:"file" description:"Dump file written by dumpwallet"`
+	} `positional-args:"yes" required:"yes"`
+	Handler *cli.WalletCliHandler
+}
+
+func (s *ImportWalletCommand) Execute(args []string) error {
+	if s.Rescan {
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.ImportWallet(s.Args.File, cli.DumpOptions{
+		PrivatePassFile: s.PrivatePassFile,
+		PassphraseFile:  s.PassphraseFile,
+	}, s.Rescan)
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type ImportAddressCommand struct {
+	Label      string `long:"label" description:"Label of the watched address"`
+	RescanFrom int32  `long:"rescan-from" description:"Block height from which the history of the address is fetched" default:"0"`
+	NoRescan   bool   `long:"no-rescan" description:"Watch the address without fetching its history now"`
+	Args       struct {
+		Address string `positional-arg-name:"address" description:"Address to watch"`
+	} `positional-args:"yes" required:"yes"`
+	Handler *cli.WalletCliHandler
+}
+
+func (s *ImportAddressCommand) Execute(args []string) error {
+	if s.RescanFrom < 0 {
+		return fmt.Errorf("--rescan-from must not be negative")
+	}
+	if !s.NoRescan {
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.ImportAddress(s.Args.Address, s.Label, s.RescanFrom, !s.NoRescan)
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type ImportPrivKeyCommand struct {
+	WIFFile         string `long:"wif-file" description:"Read the WIF from a file, - for stdin (default when stdin is not a terminal)"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from this file"`
+	Label           string `long:"label" description:"Label of the imported address"`
+	RescanFrom      int32  `long:"rescan-from" description:"Block height from which the history of the key is fetched" default:"0"`
+	NoRescan        bool   `long:"no-rescan" description:"Import the key without fetching its history"`
+	Handler         *cli.WalletCliHandler
+}
+
+func (s *ImportPrivKeyCommand) Execute(args []string) error {
+	if s.NoRescan {
+		s.RescanFrom = -1
+	} else {
+		if s.RescanFrom < 0 {
+			return fmt.Errorf("--rescan-from must not be negative")
+		}
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.ImportWithWIF(cli.ImportKeyOptions{
+		WIFFile:         s.WIFFile,
+		PrivatePassFile: s.PrivatePassFile,
+		Label:           s.Label,
+		RescanFrom:      s.RescanFrom,
+	})
+	return nil
+}
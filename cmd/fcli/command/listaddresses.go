@@ -5,15 +5,45 @@
 package command
 
 import (
+	"fmt"
+
 	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/go-flokicoin/chainutil"
 )
 
 type ListAddressesCommand struct {
+	Internal    bool    `long:"internal" description:"Only list the change addresses"`
+	External    bool    `long:"external" description:"Only list the receive addresses"`
+	Used        bool    `long:"used" description:"Only list the addresses that received funds"`
+	Unused      bool    `long:"unused" description:"Only list the addresses that never received funds"`
+	WithBalance bool    `long:"with-balance" description:"Only list the addresses holding funds"`
+	MinBalance  float64 `long:"min-balance" description:"Only list the addresses holding at least this amount"`
+	Sort        string  `long:"sort" description:"Order of the addresses" choice:"path" choice:"balance" choice:"received" default:"path"`
+
 	Handler *cli.WalletCliHandler
 }
 
 func (s *ListAddressesCommand) Execute(args []string) error {
+	if s.Internal && s.External {
+		return fmt.Errorf("--internal and --external can not be combined")
+	}
+	if s.Used && s.Unused {
+		return fmt.Errorf("--used and --unused can not be combined")
+	}
+	minBalance, err := chainutil.NewAmount(s.MinBalance)
+	if err != nil || minBalance < 0 {
+		return fmt.Errorf("invalid minimum balance: %v", s.MinBalance)
+	}
+
 	s.Handler.RequireWallet()
-	s.Handler.ListAddresses()
+	s.Handler.ListAddresses(cli.ListAddressesOptions{
+		Internal:    s.Internal,
+		External:    s.External,
+		Used:        s.Used,
+		Unused:      s.Unused,
+		WithBalance: s.WithBalance,
+		MinBalance:  minBalance,
+		Sort:        s.Sort,
+	})
 	return nil
 }
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type SignMessageCommand struct {
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS"`
+	Args            struct {
+		Address string `positional-arg-name:"address" description:"Wallet address signing the message"`
+		Message string `positional-arg-name:"message" description:"Message to sign"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *SignMessageCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.SignMessage(s.Args.Address, s.Args.Message, s.PrivatePassFile)
+	return nil
+}
+
+type VerifyMessageCommand struct {
+	Args struct {
+		Address   string `positional-arg-name:"address" description:"Address that signed the message"`
+		Signature string `positional-arg-name:"signature" description:"Base64 signature"`
+		Message   string `positional-arg-name:"message" description:"Signed message"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *VerifyMessageCommand) Execute(args []string) error {
+	s.Handler.VerifyMessage(s.Args.Address, s.Args.Signature, s.Args.Message)
+	return nil
+}
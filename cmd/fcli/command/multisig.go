@@ -0,0 +1,155 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type MultisigCommand struct{}
+
+type MultisigXpubCommand struct {
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS, when the key must be created"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigXpubCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.ShowMultisigXpub(s.PrivatePassFile)
+	return nil
+}
+
+type MultisigCreateCommand struct {
+	Threshold       int      `short:"m" long:"threshold" description:"Number of signatures required to spend" required:"yes"`
+	Cosigners       []string `long:"cosigner" description:"Extended public key of a cosigner, repeat for each of them" required:"yes"`
+	Type            string   `long:"type" description:"Script type of the addresses" choice:"p2wsh" choice:"p2sh" default:"p2wsh"`
+	PrivatePassFile string   `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS, when the key must be created"`
+	Args            struct {
+		Name string `positional-arg-name:"name" description:"Name of the multisig account"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigCreateCommand) Execute(args []string) error {
+	if s.Threshold < 1 || s.Threshold > len(s.Cosigners)+1 {
+		return fmt.Errorf("--threshold must be between 1 and %d", len(s.Cosigners)+1)
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.CreateMultisig(s.Args.Name, s.Threshold, s.Type, s.Cosigners, s.PrivatePassFile)
+	return nil
+}
+
+type MultisigListCommand struct {
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigListCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.ListMultisig()
+	return nil
+}
+
+type MultisigNewAddressCommand struct {
+	Args struct {
+		Name string `positional-arg-name:"name" description:"Name of the multisig account"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigNewAddressCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.NewMultisigAddress(s.Args.Name)
+	return nil
+}
+
+type MultisigSendCommand struct {
+	To      []string  `long:"to" description:"Destination address, repeat for each recipient" required:"yes"`
+	Amounts []float64 `long:"amount" description:"Amount in FLC, one per --to" required:"yes"`
+	FeeRate int64     `long:"fee-rate" description:"Fee rate in loki per vbyte, estimated by the server when not set"`
+	Out     string    `short:"o" long:"out" description:"File the unsigned PSBT is written to" required:"yes"`
+	Args    struct {
+		Name string `positional-arg-name:"name" description:"Name of the multisig account"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigSendCommand) Execute(args []string) error {
+	if s.FeeRate < 0 {
+		return fmt.Errorf("--fee-rate must not be negative")
+	}
+	electsrv := s.Handler.Config().ElectrumServer
+	if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+		return fmt.Errorf("failed to validate electeum server address: %v", err)
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.MultisigSend(s.Args.Name, s.To, s.Amounts, s.FeeRate, s.Out)
+	return nil
+}
+
+type MultisigSignCommand struct {
+	Out             string `short:"o" long:"out" description:"File the signed PSBT is written to, the input file by default"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS"`
+	Args            struct {
+		PSBT string `positional-arg-name:"psbt" description:"PSBT file to sign"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigSignCommand) Execute(args []string) error {
+	out := s.Out
+	if out == "" {
+		out = s.Args.PSBT
+	}
+
+	s.Handler.RequireWallet()
+	s.Handler.SignPSBT(s.Args.PSBT, out, s.PrivatePassFile)
+	return nil
+}
+
+type MultisigCombineCommand struct {
+	Out  string `short:"o" long:"out" description:"File the combined PSBT is written to" required:"yes"`
+	Args struct {
+		PSBTs []string `positional-arg-name:"psbt" description:"PSBT files signed by the cosigners" required:"2"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigCombineCommand) Execute(args []string) error {
+	s.Handler.CombinePSBTs(s.Args.PSBTs, s.Out)
+	return nil
+}
+
+type MultisigFinalizeCommand struct {
+	Broadcast bool `long:"broadcast" description:"Broadcast the transaction instead of printing it"`
+	Args      struct {
+		PSBT string `positional-arg-name:"psbt" description:"PSBT file holding enough signatures"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *MultisigFinalizeCommand) Execute(args []string) error {
+	if s.Broadcast {
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+		s.Handler.RequireWallet()
+	}
+
+	s.Handler.FinalizePSBT(s.Args.PSBT, s.Broadcast)
+	return nil
+}
@@ -5,15 +5,33 @@
 package command
 
 import (
+	"fmt"
+
 	"github.com/flokiorg/fcli/cli"
 )
 
 type NewAddressCommand struct {
+	Count uint32 `short:"n" long:"count" description:"Number of receive addresses to derive" default:"1"`
+	Out   string `short:"o" long:"out" description:"Write the addresses to a .csv or .json file"`
+	Label string `long:"label" description:"Label template of the addresses, {index} is the address index and {n} its position in the batch"`
+
 	Handler *cli.WalletCliHandler
 }
 
 func (s *NewAddressCommand) Execute(args []string) error {
+	if s.Count == 0 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+
 	s.Handler.RequireWallet()
-	s.Handler.GenerateNewAddress()
+	if s.Count == 1 && s.Out == "" && s.Label == "" {
+		s.Handler.GenerateNewAddress()
+		return nil
+	}
+	s.Handler.GenerateNewAddresses(cli.NewAddressesOptions{
+		Count:         s.Count,
+		Out:           s.Out,
+		LabelTemplate: s.Label,
+	})
 	return nil
 }
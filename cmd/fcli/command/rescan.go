@@ -0,0 +1,40 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
+)
+
+type RescanCommand struct {
+	Handler      *cli.WalletCliHandler
+	FromHeight   int32  `long:"from-height" description:"rescan from this block height instead of the wallet birthday" default:"-1"`
+	GapLimit     uint32 `long:"gap-limit" description:"Number of unused addresses in a row that ends address discovery"`
+	SaveGapLimit bool   `long:"save-gap-limit" description:"Keep --gap-limit in the wallet for later runs"`
+}
+
+func (s *RescanCommand) Execute(args []string) error {
+
+	electsrv := s.Handler.Config().ElectrumServer
+	_, err := utils.ValidateAndNormalizeURI(electsrv, 50001)
+	if err != nil {
+		return fmt.Errorf("failed to validate electeum server address: %v", err)
+	}
+
+	if s.SaveGapLimit && s.GapLimit == 0 {
+		return fmt.Errorf("--save-gap-limit requires --gap-limit")
+	}
+
+	s.Handler.UseGapLimit(s.GapLimit)
+	s.Handler.RequireWallet()
+	if s.SaveGapLimit {
+		s.Handler.PersistGapLimit(s.GapLimit)
+	}
+	s.Handler.Rescan(s.FromHeight)
+	return nil
+}
@@ -5,14 +5,52 @@
 package command
 
 import (
+	"fmt"
+
 	"github.com/flokiorg/fcli/cli"
+	"github.com/flokiorg/fcli/utils"
 )
 
 type RestoreCommand struct {
+	PublicDefault      bool   `long:"no-pubpass" description:"Do not encrypt public data (addresses, history) with a public passphrase"`
+	SeedPassphrase     bool   `long:"bip39-passphrase" description:"Derive the seed with a BIP39 passphrase (25th word)"`
+	Language           string `long:"language" description:"Mnemonic language (detected from the words when omitted)" choice:"english" choice:"spanish" choice:"french" choice:"italian" choice:"japanese" choice:"korean" choice:"czech" choice:"chinese-simplified" choice:"chinese-traditional"`
+	Words              int    `long:"words" description:"Expected number of mnemonic words" choice:"12" choice:"15" choice:"18" choice:"21" choice:"24"`
+	Shares             bool   `long:"shares" description:"Restore from SLIP-39 shares instead of a mnemonic"`
+	SharePassphrase    bool   `long:"share-passphrase" description:"The shares are protected with a passphrase"`
+	Hex                bool   `long:"hex" description:"Restore from the hex encoded seed instead of a mnemonic"`
+	MnemonicFile       string `long:"mnemonic-file" description:"Read the mnemonic (or hex seed) from a file, - for stdin"`
+	PrivatePassFile    string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS"`
+	SeedPassphraseFile string `long:"bip39-passphrase-file" description:"Read the BIP39 passphrase from a file, or set FCLI_BIP39_PASSPHRASE with --bip39-passphrase"`
+	Birthday           string `long:"birthday" description:"Date (YYYY-MM-DD) or block height from which the wallet may have history, a height requires --electserver"`
+	Recover            bool   `long:"recover" description:"Discover used addresses and their history right away, requires --electserver"`
+	GapLimit           uint32 `long:"gap-limit" description:"Number of unused addresses in a row that ends address discovery, saved in the wallet (default: 250)"`
+
 	Handler *cli.WalletCliHandler
 }
 
 func (s *RestoreCommand) Execute(args []string) error {
-	s.Handler.RestoreWallet()
+	if s.Recover {
+		electsrv := s.Handler.Config().ElectrumServer
+		if _, err := utils.ValidateAndNormalizeURI(electsrv, 50001); err != nil {
+			return fmt.Errorf("failed to validate electeum server address: %v", err)
+		}
+	}
+
+	s.Handler.RestoreWallet(cli.RestoreOptions{
+		PublicDefault:      s.PublicDefault,
+		SeedPassphrase:     s.SeedPassphrase,
+		Language:           s.Language,
+		Words:              s.Words,
+		Shares:             s.Shares,
+		SharePassphrase:    s.SharePassphrase,
+		Hex:                s.Hex,
+		MnemonicFile:       s.MnemonicFile,
+		PrivatePassFile:    s.PrivatePassFile,
+		SeedPassphraseFile: s.SeedPassphraseFile,
+		Birthday:           s.Birthday,
+		GapLimit:           s.GapLimit,
+		Recover:            s.Recover,
+	})
 	return nil
 }
@@ -0,0 +1,26 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type SeedCommand struct{}
+
+type SeedSplitCommand struct {
+	Threshold       int  `long:"threshold" description:"Number of shares needed to restore the wallet" default:"3"`
+	Shares          int  `long:"shares" description:"Number of shares to produce (at most 16)" default:"5"`
+	SeedPassphrase  bool `long:"bip39-passphrase" description:"The wallet seed is protected with a BIP39 passphrase (25th word)"`
+	SharePassphrase bool `long:"share-passphrase" description:"Protect the shares with a passphrase"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *SeedSplitCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.SplitSeed(s.Threshold, s.Shares, s.SeedPassphrase, s.SharePassphrase)
+	return nil
+}
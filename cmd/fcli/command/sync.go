@@ -12,7 +12,9 @@ import (
 )
 
 type SyncCommand struct {
-	Handler *cli.WalletCliHandler
+	Handler      *cli.WalletCliHandler
+	GapLimit     uint32 `long:"gap-limit" description:"Number of unused addresses in a row that ends address discovery"`
+	SaveGapLimit bool   `long:"save-gap-limit" description:"Keep --gap-limit in the wallet for later runs"`
 }
 
 func (s *SyncCommand) Execute(args []string) error {
@@ -23,7 +25,15 @@ func (s *SyncCommand) Execute(args []string) error {
 		return fmt.Errorf("failed to validate electeum server address: %v", err)
 	}
 
+	if s.SaveGapLimit && s.GapLimit == 0 {
+		return fmt.Errorf("--save-gap-limit requires --gap-limit")
+	}
+
+	s.Handler.UseGapLimit(s.GapLimit)
 	s.Handler.RequireWallet()
+	if s.SaveGapLimit {
+		s.Handler.PersistGapLimit(s.GapLimit)
+	}
 	s.Handler.Sync()
 	return nil
 }
@@ -9,13 +9,23 @@ import (
 )
 
 type TransactionsCommand struct {
-	Handler *cli.WalletCliHandler
-	Limit   int `long:"limit" description:"limit the number of rows to display"`
+	Handler   *cli.WalletCliHandler
+	Limit     int    `long:"limit" description:"limit the number of rows to display"`
+	WatchOnly bool   `long:"watch-only" description:"show the history of the watch-only addresses instead"`
+	Multisig  string `long:"multisig" description:"show the history of the named multisig account instead"`
 }
 
 func (s *TransactionsCommand) Execute(args []string) error {
 
 	s.Handler.RequireWallet()
+	if s.Multisig != "" {
+		s.Handler.MultisigTransactions(s.Multisig, s.Limit)
+		return nil
+	}
+	if s.WatchOnly {
+		s.Handler.WatchOnlyTransactions(s.Limit)
+		return nil
+	}
 	s.Handler.Transactions(s.Limit)
 	return nil
 }
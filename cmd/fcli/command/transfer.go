@@ -15,6 +15,7 @@ type TransferCommand struct {
 	Passphrase string  `short:"p" long:"passphrase" description:"Spending passphrase"`
 	Amount     float64 `short:"a" long:"amount" description:"Amount in FLC"`
 	Address    string  `short:"d" long:"address" description:"Destiation address"`
+	To         string  `long:"to" description:"Name of the destination in the address book"`
 
 	Handler *cli.WalletCliHandler
 }
@@ -27,6 +28,13 @@ func (s *TransferCommand) Execute(args []string) error {
 		return fmt.Errorf("failed to validate electeum server address: %v", err)
 	}
 
+	if s.To != "" {
+		if s.Address != "" {
+			return fmt.Errorf("--to and --address can not be combined")
+		}
+		s.Address = s.Handler.PayeeAddress(s.To)
+	}
+
 	s.Handler.RequireWallet()
 	s.Handler.Transfer(s.Passphrase, s.Address, s.Amount)
 	return nil
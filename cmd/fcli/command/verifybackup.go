@@ -0,0 +1,21 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type VerifyBackupCommand struct {
+	SeedPassphrase bool `long:"bip39-passphrase" description:"The wallet seed is protected with a BIP39 passphrase (25th word)"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *VerifyBackupCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.VerifyBackup(s.SeedPassphrase)
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package command
+
+import (
+	"github.com/flokiorg/fcli/cli"
+)
+
+type WalletsCommand struct{}
+
+type WalletsListCommand struct {
+	Handler *cli.WalletCliHandler
+}
+
+func (s *WalletsListCommand) Execute(args []string) error {
+	s.Handler.ListWallets()
+	return nil
+}
+
+type WalletsDefaultCommand struct {
+	Args struct {
+		Name string `positional-arg-name:"name" description:"Wallet name, 'default' for the wallet at the root of the wallet directory"`
+	} `positional-args:"yes" required:"yes"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *WalletsDefaultCommand) Execute(args []string) error {
+	s.Handler.SetDefaultWallet(s.Args.Name)
+	return nil
+}
+
+type WalletCommand struct{}
+
+type WalletDeleteCommand struct {
+	RequirePrivPass bool   `long:"require-privpass" description:"Check the private passphrase before deleting"`
+	PrivatePassFile string `long:"privpass-file" description:"Read the private passphrase from a file, or set FCLI_PRIVPASS (implies --require-privpass)"`
+
+	Handler *cli.WalletCliHandler
+}
+
+func (s *WalletDeleteCommand) Execute(args []string) error {
+	s.Handler.RequireWallet()
+	s.Handler.DeleteWallet(s.RequirePrivPass, s.PrivatePassFile)
+	return nil
+}
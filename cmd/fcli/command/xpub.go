@@ -12,12 +12,13 @@ type XpubCommand struct {
 	Index        uint32 `long:"index" short:"i" description:"branch index"`
 	WithPrivate  bool   `long:"withprivate" description:"Include private data (e.g., xpriv) in the output. Use with caution."`
 	PrintAddress bool   `long:"print-address" description:"Print address only"`
+	Type         string `long:"type" description:"Address type selecting the account scope, keys are printed as ypub or zpub for the matching scopes" choice:"p2pkh" choice:"p2sh-p2wpkh" choice:"p2wpkh" choice:"p2tr" default:"p2pkh"`
 
 	Handler *cli.WalletCliHandler
 }
 
 func (s *XpubCommand) Execute(args []string) error {
 	s.Handler.RequireWallet()
-	s.Handler.ShowXpub(s.Index, s.WithPrivate, s.PrintAddress)
+	s.Handler.ShowXpub(s.Index, s.WithPrivate, s.PrintAddress, s.Type)
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/flokiorg/fcli/cli"
 	"github.com/flokiorg/fcli/cmd/fcli/command"
+	"github.com/flokiorg/fcli/utils"
 	"github.com/flokiorg/go-flokicoin/chaincfg"
 	"github.com/flokiorg/go-flokicoin/chainutil"
 	"github.com/flokiorg/walletd/walletdb/bdb"
@@ -21,11 +22,11 @@ import (
 )
 
 var (
-	defaultDBTimeout = 10 * time.Second
-	defaultPubPass   = "/flc/public"
-	defaultWordList  = wordlists.English
-	network          = &chaincfg.MainNetParams
-	defaultAppName   = "flcwallet"
+	defaultDBTimeout  = 10 * time.Second
+	defaultPubPassEnv = "FCLI_PUBPASS"
+	defaultWordList   = wordlists.English
+	network           = &chaincfg.MainNetParams
+	defaultAppName    = "flcwallet"
 
 	defaultAccountID   uint32 = 1
 	defaultAccountName string = "myfloki"
@@ -51,8 +52,16 @@ func main() {
 	// init word list
 	bip39.SetWordList(defaultWordList)
 
+	if cfg.PublicPassword == "" && cfg.PublicPasswordFile != "" {
+		pubPass, err := utils.ReadSecretFile(cfg.PublicPasswordFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("unable to read public password file")
+		}
+		cfg.PublicPassword = string(pubPass)
+	}
+
 	if cfg.PublicPassword == "" {
-		cfg.PublicPassword = defaultPubPass
+		cfg.PublicPassword = os.Getenv(defaultPubPassEnv)
 	}
 
 	if cfg.RegressionTest {
@@ -69,6 +78,11 @@ func main() {
 		cfg.WalletDir = chainutil.AppDataDir(defaultAppName, false)
 	}
 
+	cfg.WalletPath, err = cli.WalletPath(cfg.WalletDir, cfg.Wallet)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to select wallet")
+	}
+
 	if opt := parser.FindOptionByLongName("id"); !optionDefined(opt) {
 		cfg.AccountID = defaultAccountID
 	}
@@ -83,14 +97,58 @@ func main() {
 
 	parser.AddCommand("create", "Create new wallet", "", &command.CreateCommand{Handler: handler})
 	parser.AddCommand("restore", "Restore wallet", "", &command.RestoreCommand{Handler: handler})
-
+	parser.AddCommand("changepubpass", "Encrypt public data or change its passphrase", "", &command.ChangePubPassCommand{Handler: handler})
+	parser.AddCommand("verify-backup", "Check the mnemonic backup against the wallet", "", &command.VerifyBackupCommand{Handler: handler})
+
+	seed, _ := parser.AddCommand("seed", "Manage the wallet seed", "", &command.SeedCommand{})
+	seed.AddCommand("split", "Split the seed into SLIP-39 shares", "", &command.SeedSplitCommand{Handler: handler})
+
+	wallets, _ := parser.AddCommand("wallets", "Manage the wallets of the wallet directory", "", &command.WalletsCommand{})
+	wallets.AddCommand("list", "List wallets with their network and birthday", "", &command.WalletsListCommand{Handler: handler})
+	wallets.AddCommand("default", "Select the wallet used when --wallet is not given", "", &command.WalletsDefaultCommand{Handler: handler})
+
+	wallet, _ := parser.AddCommand("wallet", "Manage the wallet in use", "", &command.WalletCommand{})
+	wallet.AddCommand("delete", "Move the wallet to the trash after confirmation", "", &command.WalletDeleteCommand{Handler: handler})
+
+	multisig, _ := parser.AddCommand("multisig", "Manage m-of-n accounts shared with cosigners", "", &command.MultisigCommand{})
+	multisig.AddCommand("xpub", "Print the extended public key to give to the cosigners", "", &command.MultisigXpubCommand{Handler: handler})
+	multisig.AddCommand("create", "Create a multisig account from the cosigner keys", "", &command.MultisigCreateCommand{Handler: handler})
+	multisig.AddCommand("list", "List multisig accounts with their balance", "", &command.MultisigListCommand{Handler: handler})
+	multisig.AddCommand("newaddress", "Create a new address of a multisig account", "", &command.MultisigNewAddressCommand{Handler: handler})
+	multisig.AddCommand("send", "Write an unsigned PSBT spending from a multisig account", "", &command.MultisigSendCommand{Handler: handler})
+	multisig.AddCommand("sign", "Add the signatures of this wallet to a PSBT", "", &command.MultisigSignCommand{Handler: handler})
+	multisig.AddCommand("combine", "Merge the signatures of several copies of a PSBT", "", &command.MultisigCombineCommand{Handler: handler})
+	multisig.AddCommand("finalize", "Complete a signed PSBT and print or broadcast it", "", &command.MultisigFinalizeCommand{Handler: handler})
+
+	descriptors, _ := parser.AddCommand("descriptors", "Export and import output descriptors", "", &command.DescriptorsCommand{})
+	descriptors.AddCommand("export", "Print the output descriptors of the accounts and watched addresses", "", &command.DescriptorsExportCommand{Handler: handler})
+	descriptors.AddCommand("import", "Create a watch-only account from output descriptors", "", &command.DescriptorsImportCommand{Handler: handler})
+
+	addressbook, _ := parser.AddCommand("addressbook", "Manage the named destinations of the wallet directory", "", &command.AddressBookCommand{})
+	addressbook.AddCommand("add", "Add a named destination", "", &command.AddressBookAddCommand{Handler: handler})
+	addressbook.AddCommand("list", "List the named destinations", "", &command.AddressBookListCommand{Handler: handler})
+	addressbook.AddCommand("remove", "Remove a named destination", "", &command.AddressBookRemoveCommand{Handler: handler})
+	addressbook.AddCommand("import", "Add the destinations of a JSON or CSV file", "", &command.AddressBookImportCommand{Handler: handler})
+	addressbook.AddCommand("export", "Write the destinations to a JSON or CSV file", "", &command.AddressBookExportCommand{Handler: handler})
+
+	parser.AddCommand("info", "Show wallet details for diagnosis", "", &command.InfoCommand{Handler: handler})
 	parser.AddCommand("addresses", "List existing addresses", "", &command.ListAddressesCommand{Handler: handler})
+	parser.AddCommand("addressinfo", "Show what is known about an address", "", &command.AddressInfoCommand{Handler: handler})
 	parser.AddCommand("newaddress", "Create new address", "", &command.NewAddressCommand{Handler: handler})
 	parser.AddCommand("xpub", "Print extended public key (xpub)", "", &command.XpubCommand{Handler: handler})
+	parser.AddCommand("derive", "Derive addresses and keys along a BIP32 path", "", &command.DeriveCommand{Handler: handler})
+	parser.AddCommand("convertkey", "Convert an extended key between the xpub, ypub and zpub formats", "", &command.ConvertKeyCommand{Handler: handler})
 	parser.AddCommand("accounts", "Display all accounts", "", &command.ListAccountsCommand{Handler: handler})
 	parser.AddCommand("balance", "Print wallet balance", "", &command.BalanceCommand{Handler: handler})
 	parser.AddCommand("transactions", "Print wallet transactions", "", &command.TransactionsCommand{Handler: handler})
 	parser.AddCommand("sync", "Sync with network", "", &command.SyncCommand{Handler: handler})
+	parser.AddCommand("rescan", "Rescan wallet history from a chosen height", "", &command.RescanCommand{Handler: handler})
+	parser.AddCommand("dumpwallet", "Write every private key of the wallet to a file", "", &command.DumpWalletCommand{Handler: handler})
+	parser.AddCommand("importwallet", "Import the keys of a wallet dump", "", &command.ImportWalletCommand{Handler: handler})
+	parser.AddCommand("importprivkey", "Import a private key (WIF) and fetch its history", "", &command.ImportPrivKeyCommand{Handler: handler})
+	parser.AddCommand("importaddress", "Watch an address without its key", "", &command.ImportAddressCommand{Handler: handler})
+	parser.AddCommand("signmessage", "Sign a message with the key of an address", "", &command.SignMessageCommand{Handler: handler})
+	parser.AddCommand("verifymessage", "Verify the signature of a message by an address", "", &command.VerifyMessageCommand{Handler: handler})
 	parser.AddCommand("transfer", "Send transaction", "", &command.TransferCommand{Handler: handler})
 	parser.AddCommand("bulktransfer", "Send transaction", "", &command.BulkTransferCommand{Handler: handler})
 	parser.AddCommand("version", "Show version", "", &command.VersionCommand{})
@@ -8,6 +8,7 @@ require (
 	github.com/jessevdk/go-flags v1.6.1
 	github.com/rs/zerolog v1.34.0
 	golang.org/x/term v0.43.0
+	golang.org/x/text v0.37.0
 )
 
 require (
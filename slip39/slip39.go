@@ -0,0 +1,524 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+// Package slip39 implements SLIP-0039, Shamir's secret sharing of a wallet
+// master secret into mnemonic shares.
+//
+// Splitting uses a single group: any threshold of the shares recovers the
+// secret. Recovery accepts multi-group share sets created by other
+// implementations.
+package slip39
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	radixBits       = 10
+	idBits          = 15
+	iterationBits   = 4
+	checksumWords   = 3
+	metadataWords   = 7
+	minSecretBytes  = 16
+	maxShareCount   = 16
+	digestBytes     = 4
+	secretIndex     = 255
+	digestIndex     = 254
+	roundCount      = 4
+	baseIterations  = 10000
+	headerBits      = idBits + 1 + iterationBits + 5*4
+	minMnemonicLen  = metadataWords + (minSecretBytes*8+radixBits-1)/radixBits
+	defaultExponent = 1
+)
+
+var (
+	customization           = []byte("shamir")
+	customizationExtendable = []byte("shamir_extendable")
+
+	rsGenerator = [10]uint32{
+		0xE0E040, 0x1C1C080, 0x3838100, 0x7070200, 0xE0E0009,
+		0x1C0C2412, 0x38086C24, 0x3090FC48, 0x21B1F890, 0x3F3F120,
+	}
+
+	ErrInvalidChecksum  = errors.New("invalid share checksum")
+	ErrInvalidDigest    = errors.New("invalid digest of the shared secret")
+	ErrNotEnoughShares  = errors.New("not enough shares to recover the secret")
+	ErrMismatchedShares = errors.New("shares do not belong to the same secret")
+)
+
+// share is a decoded share mnemonic.
+type share struct {
+	identifier        uint16
+	extendable        bool
+	iterationExponent uint8
+	groupIndex        int
+	groupThreshold    int
+	groupCount        int
+	memberIndex       int
+	memberThreshold   int
+	value             []byte
+}
+
+// Split splits the master secret so that any threshold of the returned share
+// mnemonics recovers it. The secret is encrypted with the passphrase first,
+// which may be empty.
+func Split(threshold, count int, secret, passphrase []byte) ([]string, error) {
+	if len(secret) < minSecretBytes || len(secret)%2 != 0 {
+		return nil, fmt.Errorf("master secret must be at least %d bytes long and have an even length", minSecretBytes)
+	}
+	if err := CheckSplit(threshold, count); err != nil {
+		return nil, err
+	}
+	for _, b := range passphrase {
+		if b < 32 || b > 126 {
+			return nil, errors.New("passphrase must only contain printable ASCII characters")
+		}
+	}
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	identifier := binary.BigEndian.Uint16(id[:]) & (1<<idBits - 1)
+
+	ems := encrypt(secret, passphrase, defaultExponent, identifier, true)
+
+	values, err := splitSecret(threshold, count, ems)
+	if err != nil {
+		return nil, err
+	}
+
+	mnemonics := make([]string, 0, count)
+	for i, value := range values {
+		s := share{
+			identifier:        identifier,
+			extendable:        true,
+			iterationExponent: defaultExponent,
+			groupThreshold:    1,
+			groupCount:        1,
+			memberIndex:       i,
+			memberThreshold:   threshold,
+			value:             value,
+		}
+		mnemonics = append(mnemonics, s.mnemonic())
+	}
+
+	return mnemonics, nil
+}
+
+// CheckSplit reports whether a secret can be split into count shares, any
+// threshold of which recover it.
+func CheckSplit(threshold, count int) error {
+	if count < 1 || count > maxShareCount {
+		return fmt.Errorf("share count must be between 1 and %d", maxShareCount)
+	}
+	if threshold < 1 || threshold > count {
+		return fmt.Errorf("threshold must be between 1 and the share count %d", count)
+	}
+	if threshold == 1 && count > 1 {
+		return errors.New("a threshold of 1 with several shares is not allowed, use a single share instead")
+	}
+	return nil
+}
+
+// Combine recovers the master secret from a set of share mnemonics and the
+// passphrase used when splitting it.
+func Combine(mnemonics []string, passphrase []byte) ([]byte, error) {
+	if len(mnemonics) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	shares := make([]*share, 0, len(mnemonics))
+	for i, m := range mnemonics {
+		s, err := decodeShare(m)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", i+1, err)
+		}
+		shares = append(shares, s)
+	}
+
+	first := shares[0]
+	groups := make(map[int][]*share)
+	for _, s := range shares {
+		if s.identifier != first.identifier || s.extendable != first.extendable ||
+			s.iterationExponent != first.iterationExponent ||
+			s.groupThreshold != first.groupThreshold || s.groupCount != first.groupCount ||
+			len(s.value) != len(first.value) {
+			return nil, ErrMismatchedShares
+		}
+		groups[s.groupIndex] = append(groups[s.groupIndex], s)
+	}
+
+	var groupValues []indexedValue
+	for index, members := range groups {
+		threshold := members[0].memberThreshold
+		values := make([]indexedValue, 0, len(members))
+		seen := make(map[int]bool)
+		for _, s := range members {
+			if s.memberThreshold != threshold {
+				return nil, ErrMismatchedShares
+			}
+			if seen[s.memberIndex] {
+				continue
+			}
+			seen[s.memberIndex] = true
+			values = append(values, indexedValue{s.memberIndex, s.value})
+		}
+		if len(values) < threshold {
+			continue
+		}
+
+		value, err := recoverSecret(threshold, values[:threshold])
+		if err != nil {
+			return nil, err
+		}
+		groupValues = append(groupValues, indexedValue{index, value})
+	}
+
+	if len(groupValues) < first.groupThreshold {
+		return nil, ErrNotEnoughShares
+	}
+
+	ems, err := recoverSecret(first.groupThreshold, groupValues[:first.groupThreshold])
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(ems, passphrase, first.iterationExponent, first.identifier, first.extendable), nil
+}
+
+// Threshold returns the number of shares needed to recover the secret of a
+// single group share set, read from one of its shares.
+func Threshold(mnemonic string) (int, error) {
+	s, err := decodeShare(mnemonic)
+	if err != nil {
+		return 0, err
+	}
+	return s.memberThreshold, nil
+}
+
+// mnemonic encodes the share as words.
+func (s *share) mnemonic() string {
+	valueWords := (len(s.value)*8 + radixBits - 1) / radixBits
+
+	header := uint64(s.identifier)<<25 | boolBit(s.extendable)<<24 |
+		uint64(s.iterationExponent)<<20 | uint64(s.groupIndex)<<16 |
+		uint64(s.groupThreshold-1)<<12 | uint64(s.groupCount-1)<<8 |
+		uint64(s.memberIndex)<<4 | uint64(s.memberThreshold-1)
+
+	data := new(big.Int).SetUint64(header)
+	data.Lsh(data, uint(valueWords*radixBits)).Or(data, new(big.Int).SetBytes(s.value))
+
+	indices := toWords(data, headerBits/radixBits+valueWords)
+	indices = append(indices, rsChecksum(s.customization(), indices)...)
+
+	words := make([]string, len(indices))
+	for i, index := range indices {
+		words[i] = wordList[index]
+	}
+	return strings.Join(words, " ")
+}
+
+func (s *share) customization() []byte {
+	if s.extendable {
+		return customizationExtendable
+	}
+	return customization
+}
+
+// decodeShare parses and checks a share mnemonic.
+func decodeShare(mnemonic string) (*share, error) {
+	fields := strings.Fields(strings.ToLower(mnemonic))
+	if len(fields) < minMnemonicLen {
+		return nil, fmt.Errorf("share must be at least %d words long", minMnemonicLen)
+	}
+
+	indices := make([]int, len(fields))
+	for i, w := range fields {
+		index, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("invalid word %q", w)
+		}
+		indices[i] = index
+	}
+
+	paddingBits := (radixBits * (len(indices) - metadataWords)) % 16
+	if paddingBits > 8 {
+		return nil, errors.New("invalid share length")
+	}
+
+	header := fromWords(indices[:headerBits/radixBits]).Uint64()
+	s := &share{
+		identifier:        uint16(header >> 25),
+		extendable:        header>>24&1 == 1,
+		iterationExponent: uint8(header >> 20 & 0xf),
+		groupIndex:        int(header >> 16 & 0xf),
+		groupThreshold:    int(header>>12&0xf) + 1,
+		groupCount:        int(header>>8&0xf) + 1,
+		memberIndex:       int(header >> 4 & 0xf),
+		memberThreshold:   int(header&0xf) + 1,
+	}
+
+	if rsPolymod(s.customization(), indices) != 1 {
+		return nil, ErrInvalidChecksum
+	}
+	if s.groupThreshold > s.groupCount {
+		return nil, errors.New("group threshold exceeds the number of groups")
+	}
+
+	valueIndices := indices[headerBits/radixBits : len(indices)-checksumWords]
+	valueBytes := (radixBits*len(valueIndices) - paddingBits) / 8
+	value := fromWords(valueIndices)
+	if value.BitLen() > valueBytes*8 {
+		return nil, errors.New("invalid share padding")
+	}
+	s.value = value.FillBytes(make([]byte, valueBytes))
+
+	return s, nil
+}
+
+// toWords splits a number into count 10 bit values, most significant first.
+func toWords(data *big.Int, count int) []int {
+	indices := make([]int, count)
+	mask := big.NewInt(1<<radixBits - 1)
+	for i := count - 1; i >= 0; i-- {
+		indices[i] = int(new(big.Int).And(data, mask).Int64())
+		data = new(big.Int).Rsh(data, radixBits)
+	}
+	return indices
+}
+
+// fromWords joins 10 bit values into a number.
+func fromWords(indices []int) *big.Int {
+	data := new(big.Int)
+	for _, index := range indices {
+		data.Lsh(data, radixBits).Or(data, big.NewInt(int64(index)))
+	}
+	return data
+}
+
+func boolBit(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// rsPolymod computes the RS1024 checksum polynomial over the customization
+// string and the share words.
+func rsPolymod(custom []byte, indices []int) uint32 {
+	chk := uint32(1)
+	update := func(v uint32) {
+		b := chk >> 20
+		chk = (chk&0xFFFFF)<<10 ^ v
+		for i := 0; i < 10; i++ {
+			if (b>>i)&1 == 1 {
+				chk ^= rsGenerator[i]
+			}
+		}
+	}
+	for _, c := range custom {
+		update(uint32(c))
+	}
+	for _, index := range indices {
+		update(uint32(index))
+	}
+	return chk
+}
+
+// rsChecksum returns the checksum words of the share words.
+func rsChecksum(custom []byte, indices []int) []int {
+	padded := append(append([]int(nil), indices...), make([]int, checksumWords)...)
+	polymod := rsPolymod(custom, padded) ^ 1
+
+	checksum := make([]int, checksumWords)
+	for i := range checksum {
+		checksum[i] = int(polymod>>(radixBits*(checksumWords-1-i))) & (1<<radixBits - 1)
+	}
+	return checksum
+}
+
+// encrypt runs the 4 round Feistel network protecting the master secret with
+// the passphrase.
+func encrypt(secret, passphrase []byte, exponent uint8, identifier uint16, extendable bool) []byte {
+	half := len(secret) / 2
+	l, r := secret[:half], secret[half:]
+	salt := feistelSalt(identifier, extendable)
+	for i := 0; i < roundCount; i++ {
+		l, r = r, xorBytes(l, roundFunction(i, passphrase, exponent, salt, r))
+	}
+	return append(append([]byte(nil), r...), l...)
+}
+
+// decrypt reverses encrypt.
+func decrypt(ems, passphrase []byte, exponent uint8, identifier uint16, extendable bool) []byte {
+	half := len(ems) / 2
+	l, r := ems[:half], ems[half:]
+	salt := feistelSalt(identifier, extendable)
+	for i := roundCount - 1; i >= 0; i-- {
+		l, r = r, xorBytes(l, roundFunction(i, passphrase, exponent, salt, r))
+	}
+	return append(append([]byte(nil), r...), l...)
+}
+
+func feistelSalt(identifier uint16, extendable bool) []byte {
+	if extendable {
+		return nil
+	}
+	return binary.BigEndian.AppendUint16(append([]byte(nil), customization...), identifier)
+}
+
+func roundFunction(i int, passphrase []byte, exponent uint8, salt, r []byte) []byte {
+	password := append([]byte{byte(i)}, passphrase...)
+	iterations := (baseIterations << exponent) / roundCount
+	key, _ := pbkdf2.Key(sha256.New, string(password), append(append([]byte(nil), salt...), r...), iterations, len(r))
+	return key
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// indexedValue is a share value with its x coordinate.
+type indexedValue struct {
+	index int
+	value []byte
+}
+
+// splitSecret splits the secret into count values, any threshold of which
+// recover it. A digest of the secret is hidden in the polynomial so that a
+// wrong recovery is detected.
+func splitSecret(threshold, count int, secret []byte) ([][]byte, error) {
+	if threshold == 1 {
+		values := make([][]byte, count)
+		for i := range values {
+			values[i] = append([]byte(nil), secret...)
+		}
+		return values, nil
+	}
+
+	randomCount := threshold - 2
+	base := make([]indexedValue, 0, threshold)
+	values := make([][]byte, 0, count)
+	for i := 0; i < randomCount; i++ {
+		value := make([]byte, len(secret))
+		if _, err := rand.Read(value); err != nil {
+			return nil, err
+		}
+		base = append(base, indexedValue{i, value})
+		values = append(values, value)
+	}
+
+	randomPart := make([]byte, len(secret)-digestBytes)
+	if _, err := rand.Read(randomPart); err != nil {
+		return nil, err
+	}
+	digest := append(secretDigest(randomPart, secret), randomPart...)
+	base = append(base, indexedValue{digestIndex, digest}, indexedValue{secretIndex, secret})
+
+	for i := randomCount; i < count; i++ {
+		value, err := interpolate(base, i)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// recoverSecret rebuilds the secret from threshold values and checks its
+// digest.
+func recoverSecret(threshold int, values []indexedValue) ([]byte, error) {
+	if threshold == 1 {
+		return values[0].value, nil
+	}
+
+	secret, err := interpolate(values, secretIndex)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := interpolate(values, digestIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(digest[:digestBytes], secretDigest(digest[digestBytes:], secret)) {
+		return nil, ErrInvalidDigest
+	}
+	return secret, nil
+}
+
+func secretDigest(randomPart, secret []byte) []byte {
+	mac := hmac.New(sha256.New, randomPart)
+	mac.Write(secret)
+	return mac.Sum(nil)[:digestBytes]
+}
+
+var gfExp, gfLog = func() ([255]int, [256]int) {
+	var exp [255]int
+	var log [256]int
+	poly := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = poly
+		log[poly] = i
+		// Multiply by the generator 3 modulo x^8 + x^4 + x^3 + x + 1.
+		poly = (poly << 1) ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11B
+		}
+	}
+	return exp, log
+}()
+
+// interpolate evaluates at x the Lagrange polynomial over GF(256) going
+// through the values.
+func interpolate(values []indexedValue, x int) ([]byte, error) {
+	seen := make(map[int]bool, len(values))
+	for _, v := range values {
+		if seen[v.index] {
+			return nil, errors.New("duplicate share index")
+		}
+		seen[v.index] = true
+		if len(v.value) != len(values[0].value) {
+			return nil, ErrMismatchedShares
+		}
+		if v.index == x {
+			return bytes.Clone(v.value), nil
+		}
+	}
+
+	logProd := 0
+	for _, v := range values {
+		logProd += gfLog[v.index^x]
+	}
+
+	result := make([]byte, len(values[0].value))
+	for _, v := range values {
+		logBasis := logProd - gfLog[v.index^x]
+		for _, other := range values {
+			logBasis -= gfLog[v.index^other.index]
+		}
+		logBasis = ((logBasis % 255) + 255) % 255
+
+		for i, b := range v.value {
+			if b != 0 {
+				result[i] ^= byte(gfExp[(gfLog[b]+logBasis)%255])
+			}
+		}
+	}
+	return result, nil
+}
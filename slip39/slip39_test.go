@@ -0,0 +1,190 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package slip39
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// The vectors are taken from the SLIP-0039 test vectors, all of them using the
+// passphrase "TREZOR". An empty secret means the mnemonics must be rejected.
+var vectors = []struct {
+	description string
+	mnemonics   []string
+	secret      string
+}{
+	{
+		"Valid mnemonic without sharing (128 bits)",
+		[]string{
+			"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard",
+		},
+		"bb54aac4b89dc868ba37d9cc21b2cece",
+	},
+	{
+		"Mnemonic with invalid checksum (128 bits)",
+		[]string{
+			"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision kidney",
+		},
+		"",
+	},
+	{
+		"Mnemonic with invalid padding (128 bits)",
+		[]string{
+			"duckling enlarge academic academic email result length solution fridge kidney coal piece deal husband erode duke ajar music cargo fitness",
+		},
+		"",
+	},
+	{
+		"Basic sharing 2-of-3 (128 bits)",
+		[]string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+		},
+		"b43ceb7e57a0ea8766221624d01b0864",
+	},
+	{
+		"Basic sharing 2-of-3 with a single share (128 bits)",
+		[]string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+		},
+		"",
+	},
+	{
+		"Mnemonics with different identifiers (128 bits)",
+		[]string{
+			"adequate smoking academic acid debut wine petition glen cluster slow rhyme slow simple epidemic rumor junk tracks treat olympic tolerate",
+			"adequate stay academic agency agency formal party ting frequent learn upstairs remember smear leaf damage anatomy ladle market hush corner",
+		},
+		"",
+	},
+	{
+		"Valid mnemonics of two groups (128 bits)",
+		[]string{
+			"eraser senior beard romp adorn nuclear spill corner cradle style ancient family general leader ambition exchange unusual garlic promise voice",
+			"eraser senior ceramic snake clay various huge numb argue hesitate auction category timber browser greatest hanger petition script leaf pickup",
+			"eraser senior ceramic shaft dynamic become junior wrist silver peasant force math alto coal amazing segment yelp velvet image paces",
+			"eraser senior ceramic round column hawk trust auction smug shame alive greatest sheriff living perfect corner chest sled fumes adequate",
+		},
+		"7c3397a292a5941682d7a4ae2d898d11",
+	},
+	{
+		"Valid mnemonic without sharing (256 bits)",
+		[]string{
+			"theory painting academic academic armed sweater year military elder discuss acne wildlife boring employer fused large satoshi bundle carbon diagnose anatomy hamster leaves tracks paces beyond phantom capital marvel lips brave detect luck",
+		},
+		"989baf9dcaad5b10ca33dfd8cc75e42477025dce88ae83e75a230086a0e00e92",
+	},
+	{
+		"Mnemonic with invalid checksum (256 bits)",
+		[]string{
+			"theory painting academic academic armed sweater year military elder discuss acne wildlife boring employer fused large satoshi bundle carbon diagnose anatomy hamster leaves tracks paces beyond phantom capital marvel lips brave detect lunar",
+		},
+		"",
+	},
+	{
+		"Basic sharing 2-of-3 (256 bits)",
+		[]string{
+			"humidity disease academic always aluminum jewelry energy woman receiver strategy amuse duckling lying evidence network walnut tactics forget hairy rebound impulse brother survive clothes stadium mailman rival ocean reward venture always armed unwrap",
+			"humidity disease academic agency actress jacket gross physics cylinder solution fake mortgage benefit public busy prepare sharp friar change work slow purchase ruler again tricycle involve viral wireless mixture anatomy desert cargo upgrade",
+		},
+		"c938b319067687e990e05e0da0ecce1278f75ff58d9853f19dcaeed5de104aae",
+	},
+	{
+		"Valid mnemonics which can detect some errors in modular arithmetic",
+		[]string{
+			"herald flea academic cage avoid space trend estate dryer hairy evoke eyebrow improve airline artwork garlic premium duration prevent oven",
+			"herald flea academic client blue skunk class goat luxury deny presence impulse graduate clay join blanket bulge survive dish necklace",
+			"herald flea academic acne advance fused brother frozen broken game ranked ajar already believe check install theory angry exercise adult",
+		},
+		"ad6f2ad8b59bbbaa01369b9006208d9a",
+	},
+	{
+		"Valid extendable mnemonic without sharing (128 bits)",
+		[]string{
+			"testify swimming academic academic column loyalty smear include exotic bedroom exotic wrist lobe cover grief golden smart junior estimate learn",
+		},
+		"1679b4516e0ee5954351d288a838f45e",
+	},
+	{
+		"Extendable basic sharing 2-of-3 (128 bits)",
+		[]string{
+			"enemy favorite academic acid cowboy phrase havoc level response walnut budget painting inside trash adjust froth kitchen learn tidy punish",
+			"enemy favorite academic always academic sniff script carpet romp kind promise scatter center unfair training emphasis evening belong fake enforce",
+		},
+		"48b1a4b80b8c209ad42c33672bdaa428",
+	},
+	{
+		"Valid extendable mnemonic without sharing (256 bits)",
+		[]string{
+			"impulse calcium academic academic alcohol sugar lyrics pajamas column facility finance tension extend space birthday rainbow swimming purple syndrome facility trial warn duration snapshot shadow hormone rhyme public spine counter easy hawk album",
+		},
+		"8340611602fe91af634a5f4608377b5235fa2d757c51d720c0c7656249a3035f",
+	},
+	{
+		"Extendable basic sharing 2-of-3 (256 bits)",
+		[]string{
+			"western apart academic always artist resident briefing sugar woman oven coding club ajar merit pecan answer prisoner artist fraction amount desktop mild false necklace muscle photo wealthy alpha category unwrap spew losing making",
+			"western apart academic acid answer ancient auction flip image penalty oasis beaver multiple thunder problem switch alive heat inherit superior teaspoon explain blanket pencil numb lend punish endless aunt garlic humidity kidney observe",
+		},
+		"8dc652d6d6cd370d8c963141f6d79ba440300f25c467302c1d966bff8f62300d",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		t.Run(v.description, func(t *testing.T) {
+			secret, err := Combine(v.mnemonics, []byte("TREZOR"))
+			if v.secret == "" {
+				if err == nil {
+					t.Fatalf("recovered %x from invalid mnemonics", secret)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(secret); got != v.secret {
+				t.Fatalf("recovered %s, want %s", got, v.secret)
+			}
+		})
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	for i := range secret {
+		secret[i] = byte(i)
+	}
+	passphrase := []byte("TREZOR")
+
+	mnemonics, err := Split(3, 5, secret, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mnemonics) != 5 {
+		t.Fatalf("got %d shares, want 5", len(mnemonics))
+	}
+	if threshold, err := Threshold(mnemonics[0]); err != nil || threshold != 3 {
+		t.Fatalf("got threshold %d, %v", threshold, err)
+	}
+
+	recovered, err := Combine([]string{mnemonics[4], mnemonics[0], mnemonics[2]}, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(recovered, secret) {
+		t.Fatalf("recovered %x, want %x", recovered, secret)
+	}
+
+	if _, err := Combine(mnemonics[:2], passphrase); !errors.Is(err, ErrNotEnoughShares) {
+		t.Fatalf("combining two shares of three: got %v, want %v", err, ErrNotEnoughShares)
+	}
+	if recovered, err := Combine(mnemonics[1:4], []byte("other")); err != nil || bytes.Equal(recovered, secret) {
+		t.Fatalf("a wrong passphrase must give another secret, got %x, %v", recovered, err)
+	}
+}
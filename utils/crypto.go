@@ -6,10 +6,14 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/flokiorg/go-flokicoin/chaincfg"
 	"github.com/flokiorg/go-flokicoin/chainutil"
 	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/crypto/schnorr"
+	"github.com/flokiorg/go-flokicoin/txscript"
 	"github.com/flokiorg/walletd/waddrmgr"
 )
 
@@ -36,7 +40,7 @@ func StrAddrType(t waddrmgr.AddressType) string {
 	}
 }
 
-func DeriveKeysFromXpub(network *chaincfg.Params, xpriv, xpub string, childIndex uint32) (string, string, error) {
+func DeriveKeysFromXpub(network *chaincfg.Params, xpriv, xpub string, childIndex uint32, addrType waddrmgr.AddressType) (string, string, error) {
 
 	extKey, err := hdkeychain.NewKeyFromString(xpub)
 	if err != nil {
@@ -48,7 +52,7 @@ func DeriveKeysFromXpub(network *chaincfg.Params, xpriv, xpub string, childIndex
 		return "", "", fmt.Errorf("failed to derive child key: %v", err)
 	}
 
-	add, err := childKey.Address(network)
+	add, err := KeyAddress(childKey, addrType, network)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate address: %v", err)
 	}
@@ -84,3 +88,109 @@ func DeriveKeysFromXpub(network *chaincfg.Params, xpriv, xpub string, childIndex
 
 	return add.EncodeAddress(), strWif, nil
 }
+
+// ExpandDerivationPath returns the child indexes of every path matched by a
+// BIP32 path relative to a key, such as "0/5", "1'/0h" or "0-1/0-99" where a
+// range spans its bounds. It fails when more than limit paths match.
+func ExpandDerivationPath(path string, limit int) ([][]uint32, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "m"), "/")
+	if path == "" {
+		return nil, fmt.Errorf("empty derivation path")
+	}
+
+	paths := [][]uint32{nil}
+	for _, part := range strings.Split(path, "/") {
+		offset := uint32(0)
+		if trimmed := strings.TrimRight(part, "'hH"); trimmed != part {
+			if len(part)-len(trimmed) > 1 {
+				return nil, fmt.Errorf("invalid path element %q", part)
+			}
+			part, offset = trimmed, hdkeychain.HardenedKeyStart
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		from, err := parsePathIndex(lo)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parsePathIndex(hi); err != nil {
+				return nil, err
+			}
+			if to < from {
+				return nil, fmt.Errorf("invalid range %s", part)
+			}
+		}
+
+		if count := len(paths) * int(to-from+1); to-from >= uint32(limit) || count > limit {
+			return nil, fmt.Errorf("the path matches more than %d keys", limit)
+		}
+		expanded := make([][]uint32, 0, len(paths)*int(to-from+1))
+		for _, prefix := range paths {
+			for index := from; index <= to; index++ {
+				expanded = append(expanded, append(prefix[:len(prefix):len(prefix)], index+offset))
+			}
+		}
+		paths = expanded
+	}
+	return paths, nil
+}
+
+func parsePathIndex(s string) (uint32, error) {
+	index, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || index >= hdkeychain.HardenedKeyStart {
+		return 0, fmt.Errorf("invalid path index %q", s)
+	}
+	return uint32(index), nil
+}
+
+// FormatDerivationPath writes child indexes the way ExpandDerivationPath reads
+// them, hardened ones with a quote.
+func FormatDerivationPath(path []uint32) string {
+	parts := make([]string, len(path))
+	for i, index := range path {
+		if index >= hdkeychain.HardenedKeyStart {
+			parts[i] = fmt.Sprintf("%d'", index-hdkeychain.HardenedKeyStart)
+		} else {
+			parts[i] = strconv.FormatUint(uint64(index), 10)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// KeyAddress returns the address of a key for one of the single key address
+// types, Taproot addresses commit to the key without script as in BIP-86.
+func KeyAddress(key *hdkeychain.ExtendedKey, addrType waddrmgr.AddressType, network *chaincfg.Params) (chainutil.Address, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	keyHash := chainutil.Hash160(pubKey.SerializeCompressed())
+
+	switch addrType {
+	case waddrmgr.PubKeyHash:
+		return chainutil.NewAddressPubKeyHash(keyHash, network)
+
+	case waddrmgr.NestedWitnessPubKey:
+		witnessAddr, err := chainutil.NewAddressWitnessPubKeyHash(keyHash, network)
+		if err != nil {
+			return nil, err
+		}
+		script, err := txscript.PayToAddrScript(witnessAddr)
+		if err != nil {
+			return nil, err
+		}
+		return chainutil.NewAddressScriptHash(script, network)
+
+	case waddrmgr.WitnessPubKey:
+		return chainutil.NewAddressWitnessPubKeyHash(keyHash, network)
+
+	case waddrmgr.TaprootPubKey:
+		outputKey := txscript.ComputeTaprootKeyNoScript(pubKey)
+		return chainutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), network)
+
+	default:
+		return nil, fmt.Errorf("unsupported address type %s", StrAddrType(addrType))
+	}
+}
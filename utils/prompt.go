@@ -6,8 +6,10 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -55,6 +57,58 @@ func ReadPassword(prompt string, confirm bool) []byte {
 	return password
 }
 
+// WaitForEnter prints the prompt and blocks until a line is entered.
+func WaitForEnter(prompt string) {
+	ReadInput(prompt)
+}
+
+// ReadInput prints the prompt and returns the next line of input, without
+// its line ending. It returns what was read so far at the end of the input.
+func ReadInput(prompt string) string {
+	fmt.Print(prompt)
+
+	// Read byte by byte, a buffered reader would swallow the next answers.
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := os.Stdin.Read(b[:])
+		if err != nil || (n == 1 && b[0] == '\n') {
+			return strings.TrimRight(string(line), "\r")
+		}
+		line = append(line, b[:n]...)
+	}
+}
+
+// ClearScreen clears the terminal, scrollback included, so that secrets
+// printed earlier are no longer visible.
+func ClearScreen() {
+	fmt.Print("\033[H\033[2J\033[3J")
+}
+
+// ReadSecretFile reads a secret (e.g. a passphrase) stored in a file, dropping
+// the trailing newline most editors append. A "-" path reads standard input.
+func ReadSecretFile(path string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(data, "\r\n"), nil
+}
+
+// IsTerminal reports whether standard input is a terminal, in which case the
+// user can be prompted.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 func ReadMnemonic() string {
 	fmt.Println("Enter your mnemonic phrase:")
 	fmt.Println("- If entering all words at once, type them and press Enter.")
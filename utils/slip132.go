@@ -0,0 +1,134 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/walletd/waddrmgr"
+)
+
+// KeyFormat is a SLIP-132 serialization of extended keys, its version bytes
+// tell the scripts the key is meant for.
+type KeyFormat struct {
+	// Public and Private are the prefixes of the encoded keys, such as
+	// "zpub" and "zprv".
+	Public  string
+	Private string
+
+	PublicID  [4]byte
+	PrivateID [4]byte
+
+	// Script describes the outputs the keys pay to.
+	Script string
+
+	// AddrType is the address type of single key formats, Multisig is set
+	// for the formats of multisig cosigner keys.
+	AddrType waddrmgr.AddressType
+	Multisig bool
+}
+
+// slip132Formats lists the formats of the main network and of the test
+// networks, in the same order. The first ones take the version bytes of the
+// network parameters.
+var slip132Formats = [2][]KeyFormat{
+	{
+		{Public: "xpub", Private: "xprv", Script: "p2pkh", AddrType: waddrmgr.PubKeyHash},
+		{"ypub", "yprv", [4]byte{0x04, 0x9d, 0x7c, 0xb2}, [4]byte{0x04, 0x9d, 0x78, 0x78}, "p2sh-p2wpkh", waddrmgr.NestedWitnessPubKey, false},
+		{"zpub", "zprv", [4]byte{0x04, 0xb2, 0x47, 0x46}, [4]byte{0x04, 0xb2, 0x43, 0x0c}, "p2wpkh", waddrmgr.WitnessPubKey, false},
+		{"Ypub", "Yprv", [4]byte{0x02, 0x95, 0xb4, 0x3f}, [4]byte{0x02, 0x95, 0xb0, 0x05}, "p2sh-p2wsh multisig", waddrmgr.Script, true},
+		{"Zpub", "Zprv", [4]byte{0x02, 0xaa, 0x7e, 0xd3}, [4]byte{0x02, 0xaa, 0x7a, 0x99}, "p2wsh multisig", waddrmgr.WitnessScript, true},
+	},
+	{
+		{Public: "tpub", Private: "tprv", Script: "p2pkh", AddrType: waddrmgr.PubKeyHash},
+		{"upub", "uprv", [4]byte{0x04, 0x4a, 0x52, 0x62}, [4]byte{0x04, 0x4a, 0x4e, 0x28}, "p2sh-p2wpkh", waddrmgr.NestedWitnessPubKey, false},
+		{"vpub", "vprv", [4]byte{0x04, 0x5f, 0x1c, 0xf6}, [4]byte{0x04, 0x5f, 0x18, 0xbc}, "p2wpkh", waddrmgr.WitnessPubKey, false},
+		{"Upub", "Uprv", [4]byte{0x02, 0x42, 0x89, 0xef}, [4]byte{0x02, 0x42, 0x85, 0xb5}, "p2sh-p2wsh multisig", waddrmgr.Script, true},
+		{"Vpub", "Vprv", [4]byte{0x02, 0x57, 0x54, 0x83}, [4]byte{0x02, 0x57, 0x50, 0x48}, "p2wsh multisig", waddrmgr.WitnessScript, true},
+	},
+}
+
+// KeyFormats returns the SLIP-132 formats of a network.
+func KeyFormats(network *chaincfg.Params) []KeyFormat {
+	family := 1
+	if network.HDPublicKeyID == chaincfg.MainNetParams.HDPublicKeyID {
+		family = 0
+	}
+
+	formats := append([]KeyFormat(nil), slip132Formats[family]...)
+	formats[0].PublicID, formats[0].PrivateID = network.HDPublicKeyID, network.HDPrivateKeyID
+	return formats
+}
+
+// KeyFormatByName returns the format of a network named by its public or
+// private prefix. The prefixes of the other networks are accepted too, "zpub"
+// selects "vpub" on the test networks.
+func KeyFormatByName(name string, network *chaincfg.Params) (KeyFormat, error) {
+	for _, family := range slip132Formats {
+		for i, format := range family {
+			if name == format.Public || name == format.Private {
+				return KeyFormats(network)[i], nil
+			}
+		}
+	}
+	return KeyFormat{}, fmt.Errorf("unknown extended key format %q", name)
+}
+
+// ScopeKeyFormat returns the format of the account keys of a scope, the
+// standard one for scopes SLIP-132 does not cover.
+func ScopeKeyFormat(scope waddrmgr.KeyScope, network *chaincfg.Params) KeyFormat {
+	formats := KeyFormats(network)
+	switch scope {
+	case waddrmgr.KeyScopeBIP0049Plus:
+		return formats[1]
+	case waddrmgr.KeyScopeBIP0084:
+		return formats[2]
+	default:
+		return formats[0]
+	}
+}
+
+// ParseExtendedKey decodes an extended key in any SLIP-132 format of the
+// network. The key is returned with the standard version bytes along with its
+// format.
+func ParseExtendedKey(s string, network *chaincfg.Params) (*hdkeychain.ExtendedKey, KeyFormat, error) {
+	key, err := hdkeychain.NewKeyFromString(s)
+	if err != nil {
+		return nil, KeyFormat{}, err
+	}
+
+	for _, format := range KeyFormats(network) {
+		id := format.PublicID
+		if key.IsPrivate() {
+			id = format.PrivateID
+		}
+		if !bytes.Equal(key.Version(), id[:]) {
+			continue
+		}
+		standard := network.HDPublicKeyID
+		if key.IsPrivate() {
+			standard = network.HDPrivateKeyID
+		}
+		key, err = key.CloneWithVersion(standard[:])
+		return key, format, err
+	}
+	return nil, KeyFormat{}, fmt.Errorf("the extended key is not for %s", network.Name)
+}
+
+// FormatExtendedKey encodes an extended key in a SLIP-132 format.
+func FormatExtendedKey(key *hdkeychain.ExtendedKey, format KeyFormat) (string, error) {
+	id := format.PublicID
+	if key.IsPrivate() {
+		id = format.PrivateID
+	}
+	clone, err := key.CloneWithVersion(id[:])
+	if err != nil {
+		return "", err
+	}
+	return clone.String(), nil
+}
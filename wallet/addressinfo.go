@@ -0,0 +1,179 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+	"github.com/flokiorg/walletd/wtxmgr"
+)
+
+// AddressDetails is what the wallet knows about one of its addresses.
+type AddressDetails struct {
+	Address     chainutil.Address
+	Type        waddrmgr.AddressType
+	Scope       waddrmgr.KeyScope
+	Account     uint32
+	AccountName string
+
+	// Path is the BIP32 derivation path of the key, empty for imported
+	// keys and scripts.
+	Path     string
+	Branch   uint32
+	Index    uint32
+	Imported bool
+
+	// Used is set once a transaction paid the address.
+	Used bool
+
+	// Balance sums the unspent outputs paying the address, unconfirmed
+	// ones included.
+	Balance chainutil.Amount
+
+	// Received sums the outputs ever paid to the address, only filled by
+	// AccountAddressDetails.
+	Received chainutil.Amount
+}
+
+// AddressDetails returns what the wallet knows about one of its addresses, or
+// an ErrAddressNotFound error when it does not own it.
+func (wa *WalletAccess) AddressDetails(addr chainutil.Address) (*AddressDetails, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var details *AddressDetails
+	err = walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		ma, err := wa.Manager.Address(ns, addr)
+		if err != nil {
+			return err
+		}
+		manager, account, err := wa.Manager.AddrAccount(ns, addr)
+		if err != nil {
+			return err
+		}
+		if details, err = addressDetails(ns, manager, account, ma); err != nil {
+			return err
+		}
+
+		unspent, err := wa.TxStore.UnspentOutputs(tx.ReadBucket(wtxmgrNamespaceKey))
+		if err != nil {
+			return err
+		}
+		for _, credit := range unspent {
+			if bytes.Equal(credit.PkScript, pkScript) {
+				details.Balance += credit.Amount
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// AccountAddressDetails returns the details of the addresses of an account in
+// every scope, with the total they received.
+func (wa *WalletAccess) AccountAddressDetails(account uint32) ([]*AddressDetails, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	var addrs []*AddressDetails
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		txmgrNs := tx.ReadBucket(wtxmgrNamespaceKey)
+
+		balances := make(map[string]chainutil.Amount)
+		unspent, err := wa.TxStore.UnspentOutputs(txmgrNs)
+		if err != nil {
+			return err
+		}
+		for _, credit := range unspent {
+			balances[string(credit.PkScript)] += credit.Amount
+		}
+
+		received := make(map[string]chainutil.Amount)
+		err = wa.TxStore.RangeTransactions(txmgrNs, 0, -1, func(txs []wtxmgr.TxDetails) (bool, error) {
+			for _, details := range txs {
+				for _, credit := range details.Credits {
+					pkScript := details.MsgTx.TxOut[credit.Index].PkScript
+					received[string(pkScript)] += credit.Amount
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, manager := range wa.Manager.ActiveScopedKeyManagers() {
+			err := manager.ForEachAccountAddress(ns, account, func(ma waddrmgr.ManagedAddress) error {
+				details, err := addressDetails(ns, manager, account, ma)
+				if err != nil {
+					return err
+				}
+				pkScript, err := txscript.PayToAddrScript(ma.Address())
+				if err != nil {
+					return err
+				}
+				details.Balance = balances[string(pkScript)]
+				details.Received = received[string(pkScript)]
+				addrs = append(addrs, details)
+				return nil
+			})
+			if err != nil && !waddrmgr.IsError(err, waddrmgr.ErrAccountNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
+func addressDetails(ns walletdb.ReadBucket, manager *waddrmgr.ScopedKeyManager, account uint32,
+	ma waddrmgr.ManagedAddress) (*AddressDetails, error) {
+
+	details := &AddressDetails{
+		Address:  ma.Address(),
+		Type:     ma.AddrType(),
+		Scope:    manager.Scope(),
+		Account:  account,
+		Imported: ma.Imported(),
+		Used:     ma.Used(ns),
+	}
+
+	var err error
+	if details.AccountName, err = manager.AccountName(ns, account); err != nil {
+		return nil, err
+	}
+
+	if pka, ok := ma.(waddrmgr.ManagedPubKeyAddress); ok && !ma.Imported() {
+		if scope, path, ok := pka.DerivationInfo(); ok {
+			details.Path = fmt.Sprintf("m/%d'/%d'/%d'/%d/%d",
+				scope.Purpose, scope.Coin, path.Account-hdkeychain.HardenedKeyStart,
+				path.Branch, path.Index)
+			details.Branch, details.Index = path.Branch, path.Index
+		}
+	}
+	return details, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/flokiorg/walletd/chain/electrum"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+var (
+	// waddrmgrNamespaceKey is the bucket of the address manager in the
+	// wallet database, as named by the wallet package.
+	waddrmgrNamespaceKey = []byte("waddrmgr")
+
+	// syncBucketKey and birthdayKey locate the wallet birthday within the
+	// address manager bucket, as named by the waddrmgr package.
+	syncBucketKey = []byte("sync")
+	birthdayKey   = []byte("birthday")
+)
+
+// SetBirthdayBlock records the block from which the wallet may have history.
+// The wallet is marked synced up to that block, so the first sync neither
+// walks nor scans the chain before it.
+func (wa *WalletAccess) SetBirthdayBlock(block waddrmgr.BlockStamp) error {
+	if !wa.isOpened {
+		return wallet.ErrNotLoaded
+	}
+
+	return walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		if err := wa.Manager.SetBirthday(ns, block.Timestamp); err != nil {
+			return err
+		}
+		if err := wa.Manager.SetBirthdayBlock(ns, block, true); err != nil {
+			return err
+		}
+		return wa.Manager.SetSyncedTo(ns, &block)
+	})
+}
+
+// Birthday returns the birthday of the wallet, along with its block when
+// it is already known.
+func (wa *WalletAccess) Birthday() (time.Time, *waddrmgr.BlockStamp, error) {
+	if !wa.isOpened {
+		return time.Time{}, nil, wallet.ErrNotLoaded
+	}
+
+	var block *waddrmgr.BlockStamp
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		bs, _, err := wa.Manager.BirthdayBlock(tx.ReadBucket(waddrmgrNamespaceKey))
+		if waddrmgr.IsError(err, waddrmgr.ErrBirthdayBlockNotSet) {
+			return nil
+		}
+		block = &bs
+		return err
+	})
+	return wa.Manager.Birthday(), block, err
+}
+
+// FetchBlockStamp returns the block at height from the Electrum server, or
+// the chain tip for a negative height.
+func (ws *WalletService) FetchBlockStamp(height int32) (*waddrmgr.BlockStamp, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	client := electrum.NewClient(ws.params.ElectrumServer, nil)
+	if err := client.Start(ctx); err != nil {
+		return nil, err
+	}
+	defer client.Shutdown()
+
+	if height < 0 {
+		_, tip, err := client.GetBestBlock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		height = tip
+	}
+
+	hash, header, err := client.GetBlockHash(ctx, uint32(height))
+	if err != nil {
+		return nil, err
+	}
+
+	return &waddrmgr.BlockStamp{
+		Height:    height,
+		Hash:      *hash,
+		Timestamp: header.Timestamp,
+	}, nil
+}
@@ -0,0 +1,632 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flokiorg/fcli/utils"
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+const (
+	// descriptorInputCharset and descriptorChecksumCharset are the
+	// character sets of the BIP-380 checksum.
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// watchOnlyAccountType is the type of the accounts imported from an extended
+// public key.
+const watchOnlyAccountType = 1
+
+var (
+	// mainBucketKey and masterHDPubKey locate the encrypted master public
+	// key in the address manager namespace.
+	mainBucketKey  = []byte("main")
+	masterHDPubKey = []byte("mhdpub")
+
+	// scopeBucketKey and accountBucketKey locate the account records of a
+	// scope, whose first byte is the account type.
+	scopeBucketKey   = []byte("scope")
+	accountBucketKey = []byte("acct")
+
+	// descriptorOriginsKey is the bucket of the fcli bucket holding the key
+	// origin watch-only accounts were imported with, keyed by scope and
+	// account.
+	descriptorOriginsKey = []byte("descriptor-origins")
+
+	ErrDescriptorChecksum = errors.New("invalid descriptor checksum")
+)
+
+// descriptorFuncs wraps the key of each address type, outermost function
+// first.
+var descriptorFuncs = map[waddrmgr.AddressType][]string{
+	waddrmgr.PubKeyHash:          {"pkh"},
+	waddrmgr.NestedWitnessPubKey: {"sh", "wpkh"},
+	waddrmgr.WitnessPubKey:       {"wpkh"},
+	waddrmgr.TaprootPubKey:       {"tr"},
+}
+
+// AccountDescriptors holds the output descriptors of both branches of an
+// account.
+type AccountDescriptors struct {
+	Scope    waddrmgr.KeyScope
+	Account  uint32
+	Name     string
+	External string
+	Internal string
+
+	// WatchOnly is set for accounts imported from an extended public key,
+	// exported with their public key only.
+	WatchOnly bool
+}
+
+// Descriptor is a parsed output descriptor, either of a branch of an account
+// or of a single address.
+type Descriptor struct {
+	AddrType waddrmgr.AddressType
+	Key      *hdkeychain.ExtendedKey
+
+	// Fingerprint and Path are the key origin, zero and empty when the
+	// descriptor has none.
+	Fingerprint uint32
+	Path        []uint32
+
+	// Branches are the branches the descriptor derives from Key, both for
+	// a multipath descriptor.
+	Branches []uint32
+
+	// Address is set instead of Key for addr() descriptors.
+	Address chainutil.Address
+}
+
+// origin returns the key origin of the descriptor without brackets, empty
+// when it has none.
+func (d *Descriptor) origin() string {
+	if d.Fingerprint == 0 && len(d.Path) == 0 {
+		return ""
+	}
+	origin := fmt.Sprintf("%08x", d.Fingerprint)
+	if len(d.Path) > 0 {
+		origin += "/" + utils.FormatDerivationPath(d.Path)
+	}
+	return origin
+}
+
+// DescriptorChecksum returns the BIP-380 checksum of a descriptor without
+// its checksum.
+func DescriptorChecksum(desc string) (string, error) {
+	polymod := func(c uint64, val int) uint64 {
+		c0 := c >> 35
+		c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+		for i, gen := range []uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd} {
+			if c0>>i&1 == 1 {
+				c ^= gen
+			}
+		}
+		return c
+	}
+
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for _, ch := range desc {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return "", fmt.Errorf("invalid descriptor character %q", ch)
+		}
+		c = polymod(c, pos&31)
+		cls = cls*3 + pos>>5
+		if clsCount++; clsCount == 3 {
+			c = polymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = polymod(c, cls)
+	}
+	for i := 0; i < 8; i++ {
+		c = polymod(c, 0)
+	}
+	c ^= 1
+
+	var checksum [8]byte
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[c>>(5*(7-i))&31]
+	}
+	return string(checksum[:]), nil
+}
+
+func withChecksum(desc string) (string, error) {
+	checksum, err := DescriptorChecksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return desc + "#" + checksum, nil
+}
+
+// ExportDescriptors returns the descriptors of every account with keys, the
+// private ones when private is set, which needs the wallet unlocked.
+// Watch-only accounts only have public descriptors.
+func (wa *WalletAccess) ExportDescriptors(private bool) ([]*AccountDescriptors, error) {
+	accounts, err := wa.ScopeAccounts()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := wa.masterFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	watchOnly, err := wa.WatchOnlyAccounts()
+	if err != nil {
+		return nil, err
+	}
+	type accountKey struct {
+		scope   waddrmgr.KeyScope
+		account uint32
+	}
+	imported := make(map[accountKey]bool)
+	for _, props := range watchOnly {
+		imported[accountKey{props.KeyScope, props.AccountNumber}] = true
+	}
+	origins, err := wa.descriptorOrigins()
+	if err != nil {
+		return nil, err
+	}
+
+	var descs []*AccountDescriptors
+	for _, props := range accounts {
+		// The multisig key only ever appears within multisig scripts.
+		if props.AccountPubKey == nil ||
+			(props.KeyScope == wa.params.AddressScope && props.AccountName == MultisigKeyAccount) {
+			continue
+		}
+
+		// Accounts of some scopes keep their key with a SLIP-132 version,
+		// descriptors only take the standard one.
+		key, err := props.AccountPubKey.CloneWithVersion(wa.params.Network.HDPublicKeyID[:])
+		if err != nil {
+			return nil, err
+		}
+		isWatchOnly := imported[accountKey{props.KeyScope, props.AccountNumber}]
+		if private && !isWatchOnly {
+			if props.AccountPrivKey == nil {
+				return nil, fmt.Errorf("no private key for account %q of %s", props.AccountName, props.KeyScope)
+			}
+			key, err = props.AccountPrivKey.CloneWithVersion(wa.params.Network.HDPrivateKeyID[:])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Imported keys keep the origin they came with, if any.
+		prefix := ""
+		switch {
+		case isWatchOnly:
+			if origin := origins[originKey(props.KeyScope, props.AccountNumber)]; origin != "" {
+				prefix = "[" + origin + "]"
+			}
+		case fingerprint != 0:
+			prefix = fmt.Sprintf("[%08x/%d'/%d'/%d']", fingerprint, props.KeyScope.Purpose,
+				props.KeyScope.Coin, props.AccountNumber)
+		}
+
+		schema := waddrmgr.ScopeAddrMap[props.KeyScope]
+		if props.AddrSchema != nil {
+			schema = *props.AddrSchema
+		}
+
+		ad := &AccountDescriptors{Scope: props.KeyScope, Account: props.AccountNumber, Name: props.AccountName,
+			WatchOnly: isWatchOnly}
+		for _, branch := range []struct {
+			index    uint32
+			addrType waddrmgr.AddressType
+			desc     *string
+		}{
+			{waddrmgr.ExternalBranch, schema.ExternalAddrType, &ad.External},
+			{waddrmgr.InternalBranch, schema.InternalAddrType, &ad.Internal},
+		} {
+			funcs, ok := descriptorFuncs[branch.addrType]
+			if !ok {
+				return nil, fmt.Errorf("no descriptor for %v addresses", branch.addrType)
+			}
+			desc := fmt.Sprintf("%s%s/%d/*", prefix, key, branch.index)
+			for i := len(funcs) - 1; i >= 0; i-- {
+				desc = funcs[i] + "(" + desc + ")"
+			}
+			if *branch.desc, err = withChecksum(desc); err != nil {
+				return nil, err
+			}
+		}
+		descs = append(descs, ad)
+	}
+
+	sort.Slice(descs, func(i, j int) bool {
+		if descs[i].Scope.Purpose != descs[j].Scope.Purpose {
+			return descs[i].Scope.Purpose < descs[j].Scope.Purpose
+		}
+		return descs[i].Account < descs[j].Account
+	})
+	return descs, nil
+}
+
+// AddressDescriptor returns the addr() descriptor of an address.
+func AddressDescriptor(addr chainutil.Address) (string, error) {
+	return withChecksum("addr(" + addr.EncodeAddress() + ")")
+}
+
+// masterFingerprint returns the fingerprint of the root key of the wallet, 0
+// when it is not known.
+func (wa *WalletAccess) masterFingerprint() (uint32, error) {
+	var encrypted []byte
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		main := tx.ReadBucket(waddrmgrNamespaceKey).NestedReadBucket(mainBucketKey)
+		if main != nil {
+			encrypted = append([]byte(nil), main.Get(masterHDPubKey)...)
+		}
+		return nil
+	})
+	if err != nil || len(encrypted) == 0 {
+		return 0, err
+	}
+
+	serialized, err := wa.Manager.Decrypt(waddrmgr.CKTPublic, encrypted)
+	if err != nil {
+		return 0, err
+	}
+	root, err := hdkeychain.NewKeyFromString(string(serialized))
+	if err != nil {
+		return 0, err
+	}
+	pubKey, err := root.ECPubKey()
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(chainutil.Hash160(pubKey.SerializeCompressed())[:4]), nil
+}
+
+// ParseDescriptor parses a descriptor of a branch of an account, or an addr()
+// descriptor. The checksum is checked when present.
+func ParseDescriptor(s string, net *chaincfg.Params) (*Descriptor, error) {
+	s = strings.TrimSpace(s)
+	if desc, checksum, ok := strings.Cut(s, "#"); ok {
+		expected, err := DescriptorChecksum(desc)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != expected {
+			return nil, fmt.Errorf("%w: %s, expected %s", ErrDescriptorChecksum, checksum, expected)
+		}
+		s = desc
+	}
+
+	if inner, ok := unwrap(s, "addr"); ok {
+		addr, err := chainutil.DecodeAddress(inner, net)
+		if err != nil || !addr.IsForNet(net) {
+			return nil, fmt.Errorf("invalid address %s", inner)
+		}
+		return &Descriptor{Address: addr}, nil
+	}
+
+	d := &Descriptor{}
+	key, found := "", false
+	for addrType, funcs := range descriptorFuncs {
+		inner, ok := s, true
+		for _, fn := range funcs {
+			if inner, ok = unwrap(inner, fn); !ok {
+				break
+			}
+		}
+		// sh(wpkh()) must not be taken for an unsupported sh(pkh()).
+		if ok && !strings.Contains(inner, "(") {
+			d.AddrType, key, found = addrType, inner, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unsupported descriptor %s, use pkh, wpkh, sh(wpkh), tr or addr", s)
+	}
+
+	if strings.HasPrefix(key, "[") {
+		origin, rest, ok := strings.Cut(key[1:], "]")
+		if !ok {
+			return nil, fmt.Errorf("unterminated key origin in %s", s)
+		}
+		parts := strings.Split(origin, "/")
+		fingerprint, err := hex.DecodeString(parts[0])
+		if err != nil || len(fingerprint) != 4 {
+			return nil, fmt.Errorf("invalid key origin fingerprint %s", parts[0])
+		}
+		d.Fingerprint = binary.BigEndian.Uint32(fingerprint)
+		for _, part := range parts[1:] {
+			index, err := parsePathIndex(part)
+			if err != nil {
+				return nil, err
+			}
+			d.Path = append(d.Path, index)
+		}
+		key = rest
+	}
+
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 || parts[2] != "*" {
+		return nil, fmt.Errorf("the key of %s must be an account key followed by /<branch>/*", s)
+	}
+	var err error
+	if d.Key, err = hdkeychain.NewKeyFromString(parts[0]); err != nil {
+		return nil, fmt.Errorf("invalid extended key: %v", err)
+	}
+	if !d.Key.IsForNet(net) {
+		return nil, fmt.Errorf("the extended key is not for %s", net.Name)
+	}
+
+	branches := []string{parts[1]}
+	if multi, ok := strings.CutPrefix(parts[1], "<"); ok && strings.HasSuffix(multi, ">") {
+		branches = strings.Split(strings.TrimSuffix(multi, ">"), ";")
+	}
+	for _, b := range branches {
+		branch, err := strconv.ParseUint(b, 10, 32)
+		if err != nil || uint32(branch) > waddrmgr.InternalBranch {
+			return nil, fmt.Errorf("unsupported branch %s, only 0 and 1 are", b)
+		}
+		d.Branches = append(d.Branches, uint32(branch))
+	}
+	return d, nil
+}
+
+// ImportDescriptors creates a watch-only account from descriptors of the
+// branches of the same account key. Without a descriptor of the internal
+// branch, change addresses have the type of the external ones. A gap limit of
+// addresses is derived on each branch, they are returned so that their
+// history can be fetched.
+func (wa *WalletAccess) ImportDescriptors(name string, descs []*Descriptor) (*waddrmgr.AccountProperties, []chainutil.Address, error) {
+	if !wa.isOpened {
+		return nil, nil, wallet.ErrNotLoaded
+	}
+
+	var key *hdkeychain.ExtendedKey
+	var origin *Descriptor
+	schema := waddrmgr.ScopeAddrSchema{}
+	types := make(map[uint32]waddrmgr.AddressType)
+	for _, d := range descs {
+		if d.Key == nil {
+			return nil, nil, fmt.Errorf("an address is not an account descriptor")
+		}
+		if key != nil && key.String() != d.Key.String() {
+			return nil, nil, fmt.Errorf("the descriptors have different keys")
+		}
+		key = d.Key
+		if d.origin() != "" {
+			if origin != nil && origin.origin() != d.origin() {
+				return nil, nil, fmt.Errorf("the descriptors have different key origins")
+			}
+			origin = d
+		}
+		for _, branch := range d.Branches {
+			if t, ok := types[branch]; ok && t != d.AddrType {
+				return nil, nil, fmt.Errorf("branch %d is given two address types", branch)
+			}
+			types[branch] = d.AddrType
+		}
+	}
+	external, ok := types[waddrmgr.ExternalBranch]
+	if !ok {
+		return nil, nil, fmt.Errorf("no descriptor of the external branch (/0/*)")
+	}
+	schema.ExternalAddrType = external
+	schema.InternalAddrType = external
+	if internal, ok := types[waddrmgr.InternalBranch]; ok {
+		schema.InternalAddrType = internal
+	}
+
+	// Only the public key is kept, the account is watch-only.
+	if key.IsPrivate() {
+		var err error
+		if key, err = key.Neuter(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if key.Depth() != 3 || key.ChildIndex() < hdkeychain.HardenedKeyStart {
+		return nil, nil, fmt.Errorf("the key must be an account key, of the form m/purpose'/coin_type'/account'")
+	}
+
+	var scope waddrmgr.KeyScope
+	switch external {
+	case waddrmgr.PubKeyHash:
+		scope = waddrmgr.KeyScopeBIP0044
+	case waddrmgr.NestedWitnessPubKey:
+		scope = waddrmgr.KeyScopeBIP0049Plus
+	case waddrmgr.WitnessPubKey:
+		scope = waddrmgr.KeyScopeBIP0084
+	default:
+		scope = waddrmgr.KeyScopeBIP0086
+	}
+
+	var fingerprint uint32
+	if origin != nil {
+		if len(origin.Path) > 0 && origin.Path[0] != hdkeychain.HardenedKeyStart+scope.Purpose {
+			return nil, nil, fmt.Errorf("the key origin %s does not match the descriptor type, expected purpose %d'",
+				origin.origin(), scope.Purpose)
+		}
+		fingerprint = origin.Fingerprint
+	}
+
+	props, err := wa.ImportAccountWithScope(name, key, fingerprint, scope, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manager, err := wa.Manager.FetchScopedKeyManager(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	var addrs []chainutil.Address
+	err = walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		if origin != nil {
+			meta, err := tx.CreateTopLevelBucket(metaBucketKey)
+			if err != nil {
+				return err
+			}
+			bucket, err := meta.CreateBucketIfNotExists(descriptorOriginsKey)
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(originKey(scope, props.AccountNumber)), []byte(origin.origin()))
+			if err != nil {
+				return err
+			}
+		}
+
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		last := wa.GapLimit() - 1
+		if err := manager.ExtendExternalAddresses(ns, props.AccountNumber, last); err != nil {
+			return err
+		}
+		if err := manager.ExtendInternalAddresses(ns, props.AccountNumber, last); err != nil {
+			return err
+		}
+		return manager.ForEachAccountAddress(ns, props.AccountNumber, func(ma waddrmgr.ManagedAddress) error {
+			addrs = append(addrs, ma.Address())
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return props, addrs, nil
+}
+
+// originKey is the key of the origin of an account in the descriptor origins
+// bucket.
+func originKey(scope waddrmgr.KeyScope, account uint32) string {
+	return fmt.Sprintf("%d/%d/%d", scope.Purpose, scope.Coin, account)
+}
+
+// descriptorOrigins returns the key origins of the imported accounts, keyed
+// by originKey.
+func (wa *WalletAccess) descriptorOrigins() (map[string]string, error) {
+	origins := make(map[string]string)
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		meta := tx.ReadBucket(metaBucketKey)
+		if meta == nil {
+			return nil
+		}
+		bucket := meta.NestedReadBucket(descriptorOriginsKey)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			origins[string(k)] = string(v)
+			return nil
+		})
+	})
+	return origins, err
+}
+
+// WatchOnlyAccounts returns the accounts imported from an extended public key.
+// The properties flag every account as watch-only while the wallet is locked,
+// so the account type is read from its record.
+func (wa *WalletAccess) WatchOnlyAccounts() ([]*waddrmgr.AccountProperties, error) {
+	accounts, err := wa.ScopeAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var watchOnly []*waddrmgr.AccountProperties
+	err = walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		scopes := tx.ReadBucket(waddrmgrNamespaceKey).NestedReadBucket(scopeBucketKey)
+		if scopes == nil {
+			return nil
+		}
+		for _, props := range accounts {
+			var scopeKey [8]byte
+			binary.LittleEndian.PutUint32(scopeKey[:4], props.KeyScope.Purpose)
+			binary.LittleEndian.PutUint32(scopeKey[4:], props.KeyScope.Coin)
+			scope := scopes.NestedReadBucket(scopeKey[:])
+			if scope == nil {
+				continue
+			}
+			accountBucket := scope.NestedReadBucket(accountBucketKey)
+			if accountBucket == nil {
+				continue
+			}
+			var accountKey [4]byte
+			binary.LittleEndian.PutUint32(accountKey[:], props.AccountNumber)
+			if row := accountBucket.Get(accountKey[:]); len(row) > 0 && row[0] == watchOnlyAccountType {
+				watchOnly = append(watchOnly, props)
+			}
+		}
+		return nil
+	})
+	return watchOnly, err
+}
+
+// AccountBalance returns the sum of the unspent outputs of an account,
+// unconfirmed ones included.
+func (wa *WalletAccess) AccountBalance(scope waddrmgr.KeyScope, account uint32) (chainutil.Amount, error) {
+	if !wa.isOpened {
+		return 0, wallet.ErrNotLoaded
+	}
+
+	var balance chainutil.Amount
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		unspent, err := wa.TxStore.UnspentOutputs(tx.ReadBucket(wtxmgrNamespaceKey))
+		if err != nil {
+			return err
+		}
+		for _, credit := range unspent {
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(credit.PkScript, wa.params.Network)
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+			manager, acct, err := wa.Manager.AddrAccount(ns, addrs[0])
+			if err != nil {
+				continue
+			}
+			if manager.Scope() == scope && acct == account {
+				balance += credit.Amount
+			}
+		}
+		return nil
+	})
+	return balance, err
+}
+
+// unwrap returns the argument of a descriptor function call.
+func unwrap(s, fn string) (string, bool) {
+	inner, ok := strings.CutPrefix(s, fn+"(")
+	if !ok || !strings.HasSuffix(inner, ")") {
+		return "", false
+	}
+	return strings.TrimSuffix(inner, ")"), true
+}
+
+func parsePathIndex(s string) (uint32, error) {
+	hardened := strings.HasSuffix(s, "'") || strings.HasSuffix(s, "h")
+	if hardened {
+		s = s[:len(s)-1]
+	}
+	index, err := strconv.ParseUint(s, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("invalid derivation path element %s", s)
+	}
+	if hardened {
+		index += hdkeychain.HardenedKeyStart
+	}
+	return uint32(index), nil
+}
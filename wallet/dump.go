@@ -0,0 +1,216 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/walletd/snacl"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+var (
+	// dumpMagic starts the encrypted wallet dumps, followed by the scrypt
+	// parameters of the passphrase and the sealed dump.
+	dumpMagic = []byte("FCLIDUMP\x01")
+
+	// dumpParamsSize is the size of marshalled snacl parameters.
+	dumpParamsSize = len((&snacl.SecretKey{}).Marshal())
+
+	ErrNotEncryptedDump = errors.New("not an encrypted wallet dump")
+)
+
+// KeyRecord is a private key of the wallet with what identifies it.
+type KeyRecord struct {
+	WIF     *chainutil.WIF
+	Address chainutil.Address
+
+	// Path is the BIP32 derivation path of the key, empty for imported
+	// keys.
+	Path string
+
+	// Internal tells a change key.
+	Internal bool
+
+	Label string
+}
+
+// ExportKeys returns every derived and imported private key of the wallet,
+// which must be unlocked.
+func (wa *WalletAccess) ExportKeys() ([]*KeyRecord, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	labels, err := wa.AddressLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*KeyRecord
+	err = walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		for _, manager := range wa.Manager.ActiveScopedKeyManagers() {
+			// The manager is locked while iterating, the addresses
+			// are looked up afterwards.
+			var addrs []chainutil.Address
+			err := manager.ForEachActiveAddress(ns, func(addr chainutil.Address) error {
+				addrs = append(addrs, addr)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// Derived keys come first in derivation order, then the
+			// imported ones.
+			var scoped []*KeyRecord
+			order := make(map[*KeyRecord][2]uint32)
+			for _, addr := range addrs {
+				ma, err := manager.Address(ns, addr)
+				if err != nil {
+					return err
+				}
+				pka, ok := ma.(waddrmgr.ManagedPubKeyAddress)
+				if !ok {
+					// Scripts have no key of their own.
+					continue
+				}
+
+				wif, err := pka.ExportPrivKey()
+				if waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly) {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("unable to export key of %s: %w", addr, err)
+				}
+
+				record := &KeyRecord{
+					WIF:      wif,
+					Address:  addr,
+					Internal: pka.Internal(),
+					Label:    labels[addr.EncodeAddress()],
+				}
+				if scope, path, ok := pka.DerivationInfo(); ok && !pka.Imported() {
+					record.Path = fmt.Sprintf("m/%d'/%d'/%d'/%d/%d",
+						scope.Purpose, scope.Coin, path.Account-hdkeychain.HardenedKeyStart,
+						path.Branch, path.Index)
+					order[record] = [2]uint32{path.Branch, path.Index}
+				}
+				scoped = append(scoped, record)
+			}
+
+			sort.SliceStable(scoped, func(i, j int) bool {
+				oi, iok := order[scoped[i]]
+				oj, jok := order[scoped[j]]
+				if iok != jok {
+					return iok
+				}
+				if oi[0] != oj[0] {
+					return oi[0] < oj[0]
+				}
+				if oi[1] != oj[1] {
+					return oi[1] < oj[1]
+				}
+				return scoped[i].Address.EncodeAddress() < scoped[j].Address.EncodeAddress()
+			})
+			records = append(records, scoped...)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// ScopeForAddress returns the key scope whose addresses have the type of addr.
+func ScopeForAddress(addr chainutil.Address) (waddrmgr.KeyScope, error) {
+	switch addr.(type) {
+	case *chainutil.AddressPubKeyHash:
+		return waddrmgr.KeyScopeBIP0044, nil
+	case *chainutil.AddressScriptHash:
+		return waddrmgr.KeyScopeBIP0049Plus, nil
+	case *chainutil.AddressWitnessPubKeyHash:
+		return waddrmgr.KeyScopeBIP0084, nil
+	case *chainutil.AddressTaproot:
+		return waddrmgr.KeyScopeBIP0086, nil
+	}
+	return waddrmgr.KeyScope{}, fmt.Errorf("unsupported address type %T", addr)
+}
+
+// ImportKey adds a private key to the imported account of scope, which must
+// be unlocked. The key may have history from bs on, from the genesis block
+// when nil. It does not need the chain, fetching the history of the key is
+// left to the caller.
+func (wa *WalletAccess) ImportKey(scope waddrmgr.KeyScope, wif *chainutil.WIF, bs *waddrmgr.BlockStamp) (chainutil.Address, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	manager, err := wa.Manager.FetchScopedKeyManager(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var addr chainutil.Address
+	err = walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		ma, err := manager.ImportPrivateKey(tx.ReadWriteBucket(waddrmgrNamespaceKey), wif, bs)
+		if err != nil {
+			return err
+		}
+		addr = ma.Address()
+		return nil
+	})
+	return addr, err
+}
+
+// SealDump encrypts a wallet dump with a passphrase.
+func SealDump(dump, passphrase []byte) ([]byte, error) {
+	key, err := snacl.NewSecretKey(&passphrase, snacl.DefaultN, snacl.DefaultR, snacl.DefaultP)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	sealed, err := key.Encrypt(dump)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(dumpMagic)
+	buf.Write(key.Marshal())
+	buf.Write(sealed)
+	return buf.Bytes(), nil
+}
+
+// IsSealedDump reports whether data is an encrypted wallet dump.
+func IsSealedDump(data []byte) bool {
+	return bytes.HasPrefix(data, dumpMagic)
+}
+
+// OpenDump decrypts a wallet dump sealed with SealDump.
+func OpenDump(data, passphrase []byte) ([]byte, error) {
+	if !IsSealedDump(data) || len(data) < len(dumpMagic)+dumpParamsSize {
+		return nil, ErrNotEncryptedDump
+	}
+	data = data[len(dumpMagic):]
+
+	var key snacl.SecretKey
+	if err := key.Unmarshal(data[:dumpParamsSize]); err != nil {
+		return nil, err
+	}
+	if err := key.DeriveKey(&passphrase); err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	return key.Decrypt(data[dumpParamsSize:])
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"encoding/binary"
+	"slices"
+
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+var (
+	// DefaultGapLimit is the number of unused addresses in a row after which
+	// a branch is considered exhausted, unless the wallet sets its own.
+	DefaultGapLimit uint32 = 250
+
+	gapLimitKey = []byte("gap-limit")
+)
+
+// GapLimit returns the gap limit in use: the one set for this session, else
+// the one stored in the wallet, else DefaultGapLimit.
+func (wa *WalletAccess) GapLimit() uint32 {
+	if wa.params.GapLimit > 0 {
+		return wa.params.GapLimit
+	}
+	if limit := wa.storedGapLimit(); limit > 0 {
+		return limit
+	}
+	return DefaultGapLimit
+}
+
+// UseGapLimit overrides the gap limit for the next wallet creation or
+// opening, without storing it. Zero restores the stored or default limit.
+func (wa *WalletAccess) UseGapLimit(limit uint32) {
+	wa.params.GapLimit = limit
+}
+
+// SaveGapLimit stores the gap limit in the wallet, it is used by every later
+// recovery, sync and rescan.
+func (wa *WalletAccess) SaveGapLimit(limit uint32) error {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, limit)
+	return wa.putMeta(gapLimitKey, value)
+}
+
+// storedGapLimit returns the gap limit stored in the wallet, zero when unset.
+// The loader takes the limit before the wallet is opened, so the database is
+// read on its own when needed.
+func (wa *WalletAccess) storedGapLimit() uint32 {
+	if wa.isOpened {
+		value, err := wa.getMeta(gapLimitKey)
+		if err != nil || len(value) != 4 {
+			return 0
+		}
+		return binary.BigEndian.Uint32(value)
+	}
+
+	if exists, _ := wa.WalletExists(); !exists {
+		return 0
+	}
+
+	var limit uint32
+	viewWalletFile(wa.params.Path, wa.params.Timeout, func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket(metaBucketKey)
+		if bucket == nil {
+			return nil
+		}
+		if value := bucket.Get(gapLimitKey); len(value) == 4 {
+			limit = binary.BigEndian.Uint32(value)
+		}
+		return nil
+	})
+	return limit
+}
+
+// LargestAddressGap returns the longest run of unused addresses followed by a
+// used one, over both branches of the opened account. A run close to the gap
+// limit hints at funds hidden beyond it.
+func (wa *WalletAccess) LargestAddressGap() (uint32, error) {
+	if !wa.isOpened || wa.account == nil {
+		return 0, wallet.ErrNotLoaded
+	}
+
+	manager, err := wa.Manager.FetchScopedKeyManager(wa.params.AddressScope)
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[uint32][]uint32)
+	err = walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		return manager.ForEachAccountAddress(ns, wa.account.AccountNumber, func(ma waddrmgr.ManagedAddress) error {
+			pka, ok := ma.(waddrmgr.ManagedPubKeyAddress)
+			if !ok || !ma.Used(ns) {
+				return nil
+			}
+			if _, path, ok := pka.DerivationInfo(); ok {
+				used[path.Branch] = append(used[path.Branch], path.Index)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var largest uint32
+	for _, indexes := range used {
+		slices.Sort(indexes)
+		var next uint32
+		for _, index := range indexes {
+			largest = max(largest, index-next)
+			next = index + 1
+		}
+	}
+	return largest, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"path/filepath"
+
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+// WalletFile returns the path of the wallet database.
+func (wa *WalletAccess) WalletFile() string {
+	return filepath.Join(wa.params.Path, wallet.WalletDBName)
+}
+
+// ScopeAccounts returns the properties of the accounts of every active key
+// scope of the wallet, imported accounts included.
+func (wa *WalletAccess) ScopeAccounts() ([]*waddrmgr.AccountProperties, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	var accounts []*waddrmgr.AccountProperties
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		for _, manager := range wa.Manager.ActiveScopedKeyManagers() {
+			err := manager.ForEachAccount(ns, func(account uint32) error {
+				props, err := manager.AccountProperties(ns, account)
+				if err != nil {
+					return err
+				}
+				accounts = append(accounts, props)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return accounts, err
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+// addressLabelsKey is the bucket of the fcli bucket holding address labels,
+// keyed by encoded address.
+var addressLabelsKey = []byte("address-labels")
+
+// SetAddressLabel labels an address of the wallet, an empty label removes it.
+func (wa *WalletAccess) SetAddressLabel(addr chainutil.Address, label string) error {
+	if !wa.isOpened {
+		return wallet.ErrNotLoaded
+	}
+
+	return walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		meta, err := tx.CreateTopLevelBucket(metaBucketKey)
+		if err != nil {
+			return err
+		}
+		bucket, err := meta.CreateBucketIfNotExists(addressLabelsKey)
+		if err != nil {
+			return err
+		}
+		if label == "" {
+			return bucket.Delete([]byte(addr.EncodeAddress()))
+		}
+		return bucket.Put([]byte(addr.EncodeAddress()), []byte(label))
+	})
+}
+
+// AddressLabels returns the labels of the wallet addresses, keyed by encoded
+// address.
+func (wa *WalletAccess) AddressLabels() (map[string]string, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	labels := make(map[string]string)
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		meta := tx.ReadBucket(metaBucketKey)
+		if meta == nil {
+			return nil
+		}
+		bucket := meta.NestedReadBucket(addressLabelsKey)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			labels[string(k)] = string(v)
+			return nil
+		})
+	})
+	return labels, err
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chaincfg/chainhash"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	btcec "github.com/flokiorg/go-flokicoin/crypto"
+	"github.com/flokiorg/go-flokicoin/crypto/ecdsa"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/wallet"
+)
+
+// messageMagic prefixes the messages signed in the legacy format so that a
+// signature can never be a valid transaction signature.
+const messageMagic = "Flokicoin Signed Message:\n"
+
+// bip322Tag is the tag of the BIP-322 message hash.
+var bip322Tag = []byte("BIP0322-signed-message")
+
+var ErrInvalidSignature = errors.New("the signature does not match the address and message")
+
+// SignMessage signs a message with the key of a wallet address and returns
+// the base64 signature. Pay-to-pubkey-hash addresses use the legacy compact
+// format, SegWit and Taproot addresses the BIP-322 simple format, or the full
+// format for nested SegWit. The wallet must be unlocked.
+func (wa *WalletAccess) SignMessage(addr chainutil.Address, message string) (string, error) {
+	if !wa.isOpened {
+		return "", wallet.ErrNotLoaded
+	}
+
+	privKey, err := wa.PrivKeyForAddress(addr)
+	if err != nil {
+		return "", err
+	}
+
+	switch addr.(type) {
+	case *chainutil.AddressPubKeyHash:
+		sig := ecdsa.SignCompact(privKey, legacyMessageHash(message), true)
+		return base64.StdEncoding.EncodeToString(sig), nil
+
+	case *chainutil.AddressWitnessPubKeyHash, *chainutil.AddressTaproot, *chainutil.AddressScriptHash:
+		return signBIP322(addr, privKey, message, wa.params.Network)
+
+	default:
+		return "", fmt.Errorf("unable to sign with a %T", addr)
+	}
+}
+
+// VerifyMessage checks a signature made by SignMessage, or by any wallet
+// following the legacy or BIP-322 formats. It returns ErrInvalidSignature
+// when the signature is well formed but does not match.
+func VerifyMessage(addr chainutil.Address, signature, message string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+
+	// Compact signatures are always 65 bytes long, a BIP-322 signature of
+	// a pay-to-pubkey-hash address is a full transaction.
+	if _, ok := addr.(*chainutil.AddressPubKeyHash); ok && len(sig) == 65 {
+		return verifyLegacy(addr, sig, message)
+	}
+	return verifyBIP322(addr, sig, message)
+}
+
+func legacyMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, messageMagic)
+	_ = wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+func verifyLegacy(addr chainutil.Address, sig []byte, message string) error {
+	pubKey, compressed, err := ecdsa.RecoverCompact(sig, legacyMessageHash(message))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+	if !bytes.Equal(chainutil.Hash160(serialized), addr.ScriptAddress()) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// bip322ToSpend returns the virtual transaction BIP-322 signatures spend, it
+// pays the address and commits to the message.
+func bip322ToSpend(pkScript []byte, message string) (*wire.MsgTx, error) {
+	hash := chainhash.TaggedHash(bip322Tag, []byte(message))
+	scriptSig, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash[:]).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(0)
+	prevOut := wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex)
+	in := wire.NewTxIn(prevOut, scriptSig, nil)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	return tx, nil
+}
+
+// bip322ToSign returns the unsigned virtual transaction spending toSpend.
+func bip322ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	in := wire.NewTxIn(&wire.OutPoint{Hash: toSpend.TxHash(), Index: 0}, nil, nil)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return tx
+}
+
+func signBIP322(addr chainutil.Address, privKey *btcec.PrivateKey, message string, net *chaincfg.Params) (string, error) {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return "", err
+	}
+	toSpend, err := bip322ToSpend(pkScript, message)
+	if err != nil {
+		return "", err
+	}
+	toSign := bip322ToSign(toSpend)
+
+	prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	sigHashes := txscript.NewTxSigHashes(toSign, prevOuts)
+
+	switch addr.(type) {
+	case *chainutil.AddressTaproot:
+		toSign.TxIn[0].Witness, err = txscript.TaprootWitnessSignature(toSign, sigHashes, 0, 0,
+			pkScript, txscript.SigHashDefault, privKey)
+
+	case *chainutil.AddressScriptHash:
+		// Only nested pay-to-witness-pubkey-hash is signed, the redeem
+		// script goes in the signature script so the full format is used.
+		var witnessAddr *chainutil.AddressWitnessPubKeyHash
+		witnessAddr, err = chainutil.NewAddressWitnessPubKeyHash(
+			chainutil.Hash160(privKey.PubKey().SerializeCompressed()), net)
+		if err != nil {
+			return "", err
+		}
+		var redeemScript []byte
+		if redeemScript, err = txscript.PayToAddrScript(witnessAddr); err != nil {
+			return "", err
+		}
+		if !bytes.Equal(chainutil.Hash160(redeemScript), addr.ScriptAddress()) {
+			return "", fmt.Errorf("unable to sign with a non-standard script address")
+		}
+		if toSign.TxIn[0].SignatureScript, err = txscript.NewScriptBuilder().AddData(redeemScript).Script(); err != nil {
+			return "", err
+		}
+		toSign.TxIn[0].Witness, err = txscript.WitnessSignature(toSign, sigHashes, 0, 0,
+			redeemScript, txscript.SigHashAll, privKey, true)
+
+	default:
+		toSign.TxIn[0].Witness, err = txscript.WitnessSignature(toSign, sigHashes, 0, 0,
+			pkScript, txscript.SigHashAll, privKey, true)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if len(toSign.TxIn[0].SignatureScript) > 0 {
+		err = toSign.Serialize(&buf)
+	} else {
+		err = writeWitness(&buf, toSign.TxIn[0].Witness)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func verifyBIP322(addr chainutil.Address, sig []byte, message string) error {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	toSpend, err := bip322ToSpend(pkScript, message)
+	if err != nil {
+		return err
+	}
+
+	// The simple format is the witness of the input, the full format the
+	// whole signed transaction.
+	toSign := bip322ToSign(toSpend)
+	witness, err := readWitness(sig)
+	if err == nil {
+		toSign.TxIn[0].Witness = witness
+	} else {
+		full := &wire.MsgTx{}
+		if err := full.Deserialize(bytes.NewReader(sig)); err != nil {
+			return fmt.Errorf("invalid BIP-322 signature: %v", err)
+		}
+		if len(full.TxIn) != 1 || len(full.TxOut) != 1 ||
+			full.TxIn[0].PreviousOutPoint != toSign.TxIn[0].PreviousOutPoint ||
+			full.TxOut[0].Value != 0 || !bytes.Equal(full.TxOut[0].PkScript, []byte{txscript.OP_RETURN}) {
+			return ErrInvalidSignature
+		}
+		toSign = full
+	}
+
+	prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	engine, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags,
+		nil, txscript.NewTxSigHashes(toSign, prevOuts), 0, prevOuts)
+	if err != nil {
+		return err
+	}
+	if err := engine.Execute(); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// writeWitness encodes a witness stack as in transactions.
+func writeWitness(w io.Writer, witness wire.TxWitness) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(w, 0, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readWitness decodes a witness stack, failing unless it spans all of data.
+func readWitness(data []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(data)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || count > uint64(txscript.MaxStackSize) {
+		return nil, fmt.Errorf("invalid witness item count %d", count)
+	}
+
+	var witness wire.TxWitness
+	for i := uint64(0); i < count; i++ {
+		item, err := wire.ReadVarBytes(r, 0, txscript.MaxScriptSize, "witness item")
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after the witness", r.Len())
+	}
+	return witness, nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+var (
+	// metaBucketKey is the top-level bucket of the wallet database holding
+	// the settings fcli keeps alongside the wallet.
+	metaBucketKey = []byte("fcli")
+
+	backupVerifiedKey = []byte("backup-verified")
+	networkKey        = []byte("network")
+)
+
+// WalletSummary describes a wallet database that is not loaded.
+type WalletSummary struct {
+	// Network is the name of the network the wallet belongs to, empty for
+	// wallets created before it was recorded.
+	Network string
+
+	// Birthday is the earliest time the wallet may have history.
+	Birthday time.Time
+}
+
+// putMeta stores a value in the fcli bucket of the wallet database.
+func (wa *WalletAccess) putMeta(key, value []byte) error {
+	if !wa.isOpened {
+		return wallet.ErrNotLoaded
+	}
+
+	return walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket(metaBucketKey)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+}
+
+// getMeta returns a value of the fcli bucket, nil when it was never set.
+func (wa *WalletAccess) getMeta(key []byte) ([]byte, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	var value []byte
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket(metaBucketKey)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get(key); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// viewWalletFile runs fn in a read transaction of the wallet database found in
+// dir, without loading the wallet nor needing its public passphrase.
+func viewWalletFile(dir string, timeout time.Duration, fn func(tx walletdb.ReadTx) error) error {
+	dbPath := filepath.Join(dir, wallet.WalletDBName)
+	db, err := walletdb.Open("bdb", dbPath, true, timeout, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return walletdb.View(db, fn)
+}
+
+// ReadWalletSummary returns the summary of the wallet database found in dir.
+func ReadWalletSummary(dir string, timeout time.Duration) (*WalletSummary, error) {
+	summary := &WalletSummary{}
+	err := viewWalletFile(dir, timeout, func(tx walletdb.ReadTx) error {
+		if bucket := tx.ReadBucket(metaBucketKey); bucket != nil {
+			summary.Network = string(bucket.Get(networkKey))
+		}
+
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		if ns == nil {
+			return fmt.Errorf("not a wallet database")
+		}
+		if sync := ns.NestedReadBucket(syncBucketKey); sync != nil {
+			if value := sync.Get(birthdayKey); len(value) == 8 {
+				summary.Birthday = time.Unix(int64(binary.BigEndian.Uint64(value)), 0)
+			}
+		}
+		return nil
+	})
+	return summary, err
+}
+
+// checkNetwork makes sure the opened wallet belongs to the configured
+// network, recording it for wallets that predate the check.
+func (wa *WalletAccess) checkNetwork() error {
+	network, err := wa.getMeta(networkKey)
+	if err != nil {
+		return err
+	}
+	if network == nil {
+		return wa.putMeta(networkKey, []byte(wa.params.Network.Name))
+	}
+	if string(network) != wa.params.Network.Name {
+		return fmt.Errorf("the wallet belongs to the %s network, not %s", network, wa.params.Network.Name)
+	}
+	return nil
+}
+
+// SetBackupVerified records whether the user proved to hold the seed backup.
+func (wa *WalletAccess) SetBackupVerified(verified bool) error {
+	value := []byte{0}
+	if verified {
+		value[0] = 1
+	}
+	return wa.putMeta(backupVerifiedKey, value)
+}
+
+// BackupVerified reports whether the seed backup of the wallet was verified.
+func (wa *WalletAccess) BackupVerified() (bool, error) {
+	value, err := wa.getMeta(backupVerifiedKey)
+	if err != nil {
+		return false, err
+	}
+	return len(value) == 1 && value[0] == 1, nil
+}
+
+// MatchesSeed reports whether the seed derives the account key of the opened
+// account. The wallet does not keep its seed, this is how a backup is checked
+// once the mnemonic is no longer on screen.
+func (wa *WalletAccess) MatchesSeed(seed []byte) (bool, error) {
+	if !wa.isOpened || wa.account == nil {
+		return false, wallet.ErrNotLoaded
+	}
+
+	key, err := hdkeychain.NewMaster(seed, wa.params.Network)
+	if err != nil {
+		return false, err
+	}
+
+	scope := wa.params.AddressScope
+	for _, index := range []uint32{scope.Purpose, scope.Coin, wa.account.AccountNumber} {
+		key, err = key.Derive(index + hdkeychain.HardenedKeyStart)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return false, err
+	}
+	accountPubKey, err := wa.account.AccountPubKey.ECPubKey()
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(pubKey.SerializeCompressed(), accountPubKey.SerializeCompressed()) &&
+		bytes.Equal(key.ChainCode(), wa.account.AccountPubKey.ChainCode()), nil
+}
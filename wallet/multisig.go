@@ -0,0 +1,414 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/flokiorg/fcli/utils"
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+const (
+	// MultisigP2WSH pays multisig accounts to native SegWit script hashes.
+	MultisigP2WSH = "p2wsh"
+
+	// MultisigP2SH pays multisig accounts to legacy script hashes.
+	MultisigP2SH = "p2sh"
+)
+
+var (
+	// multisigKey is the bucket of the fcli bucket holding the multisig
+	// accounts, keyed by name.
+	multisigKey = []byte("multisig")
+
+	// MultisigKeyAccount names the wallet account whose extended public key
+	// is shared with the cosigners of the multisig accounts.
+	MultisigKeyAccount = "multisig"
+
+	// multisigLookahead is the number of unused addresses past the last
+	// used or handed out one looked up on each branch, cosigners being
+	// able to hand out addresses too.
+	multisigLookahead uint32 = 20
+
+	// maxMultisigKeys is the largest number of keys a legacy P2SH redeem
+	// script can hold.
+	maxMultisigKeys = 15
+
+	ErrNoMultisigKey    = errors.New("the wallet has no multisig key yet")
+	ErrMultisigExists   = errors.New("a multisig account with this name already exists")
+	ErrMultisigNotFound = errors.New("multisig account not found")
+)
+
+// MultisigAddress is an address of a multisig account with its history.
+type MultisigAddress struct {
+	WatchedAddress
+
+	Branch uint32
+	Index  uint32
+}
+
+// MultisigAccount is an m-of-n account shared with cosigners. Its addresses
+// are derived from the account extended public keys of every cosigner, the
+// wallet included, with the keys sorted in the scripts. Its history is kept
+// apart from the wallet transactions, like watch-only addresses.
+type MultisigAccount struct {
+	Name      string
+	Threshold int
+
+	// Type is MultisigP2WSH or MultisigP2SH.
+	Type string
+
+	// Keys holds the extended public keys of all cosigners, sorted.
+	Keys []string
+
+	// OwnKey is the extended public key of the wallet among Keys.
+	OwnKey string
+
+	// ExternalCount and InternalCount are the number of addresses handed
+	// out or found used on each branch.
+	ExternalCount uint32
+	InternalCount uint32
+
+	// Addresses holds the addresses looked up so far.
+	Addresses []*MultisigAddress
+}
+
+// Balance returns the balance of the account as of its last refresh.
+func (m *MultisigAccount) Balance() chainutil.Amount {
+	var balance chainutil.Amount
+	for _, ma := range m.Addresses {
+		balance += ma.Balance
+	}
+	return balance
+}
+
+// count returns the number of addresses in use on a branch.
+func (m *MultisigAccount) count(branch uint32) *uint32 {
+	if branch == waddrmgr.InternalBranch {
+		return &m.InternalCount
+	}
+	return &m.ExternalCount
+}
+
+// Script returns the multisig script of an address and the address paying to
+// it.
+func (m *MultisigAccount) Script(branch, index uint32, network *chaincfg.Params) ([]byte, chainutil.Address, error) {
+	pubKeys := make([][]byte, 0, len(m.Keys))
+	for _, xpub := range m.Keys {
+		key, err := hdkeychain.NewKeyFromString(xpub)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key, err = key.Derive(branch); err != nil {
+			return nil, nil, err
+		}
+		if key, err = key.Derive(index); err != nil {
+			return nil, nil, err
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKeys = append(pubKeys, pubKey.SerializeCompressed())
+	}
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+	})
+
+	builder := txscript.NewScriptBuilder().AddInt64(int64(m.Threshold))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+	script, err := builder.AddInt64(int64(len(pubKeys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var addr chainutil.Address
+	if m.Type == MultisigP2SH {
+		addr, err = chainutil.NewAddressScriptHash(script, network)
+	} else {
+		hash := sha256.Sum256(script)
+		addr, err = chainutil.NewAddressWitnessScriptHash(hash[:], network)
+	}
+	return script, addr, err
+}
+
+// address returns the record of an address, adding it if needed.
+func (m *MultisigAccount) address(branch, index uint32, network *chaincfg.Params) (*MultisigAddress, error) {
+	for _, ma := range m.Addresses {
+		if ma.Branch == branch && ma.Index == index {
+			return ma, nil
+		}
+	}
+
+	_, addr, err := m.Script(branch, index, network)
+	if err != nil {
+		return nil, err
+	}
+	ma := &MultisigAddress{
+		WatchedAddress: WatchedAddress{Address: addr.EncodeAddress()},
+		Branch:         branch,
+		Index:          index,
+	}
+	m.Addresses = append(m.Addresses, ma)
+	return ma, nil
+}
+
+// MultisigXpub returns the extended public key the wallet shares with its
+// cosigners, ErrNoMultisigKey until CreateMultisigKey was called.
+func (wa *WalletAccess) MultisigXpub() (*hdkeychain.ExtendedKey, error) {
+	props, err := wa.multisigKeyAccount()
+	if err != nil {
+		return nil, err
+	}
+	return props.AccountPubKey, nil
+}
+
+// CreateMultisigKey adds the account holding the key of the wallet in its
+// multisig accounts. The wallet must be unlocked.
+func (wa *WalletAccess) CreateMultisigKey() (*hdkeychain.ExtendedKey, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+	if _, err := wa.NextAccount(wa.params.AddressScope, MultisigKeyAccount); err != nil {
+		return nil, err
+	}
+	return wa.MultisigXpub()
+}
+
+func (wa *WalletAccess) multisigKeyAccount() (*waddrmgr.AccountProperties, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+	account, err := wa.AccountNumber(wa.params.AddressScope, MultisigKeyAccount)
+	if waddrmgr.IsError(err, waddrmgr.ErrAccountNotFound) {
+		return nil, ErrNoMultisigKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wa.AccountProperties(wa.params.AddressScope, account)
+}
+
+// CreateMultisigAccount adds an m-of-n account made of the multisig key of the
+// wallet and the account extended public keys of the cosigners.
+func (wa *WalletAccess) CreateMultisigAccount(name string, threshold int, scriptType string, cosigners []string) (*MultisigAccount, error) {
+	own, err := wa.MultisigXpub()
+	if err != nil {
+		return nil, err
+	}
+
+	if scriptType != MultisigP2WSH && scriptType != MultisigP2SH {
+		return nil, fmt.Errorf("unknown multisig type %q, use %s or %s", scriptType, MultisigP2WSH, MultisigP2SH)
+	}
+
+	keys := []string{own.String()}
+	for _, cosigner := range cosigners {
+		// Cosigners may hand their key in any SLIP-132 format, it is
+		// kept with the standard version.
+		key, _, err := utils.ParseExtendedKey(cosigner, wa.params.Network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cosigner key %s: %v", cosigner, err)
+		}
+		if key.IsPrivate() {
+			return nil, fmt.Errorf("cosigner key %s is private, share the extended public key", cosigner)
+		}
+		for _, k := range keys {
+			if k == key.String() {
+				return nil, fmt.Errorf("key %s is given twice", cosigner)
+			}
+		}
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	if len(keys) < 2 || len(keys) > maxMultisigKeys {
+		return nil, fmt.Errorf("a multisig account needs 2 to %d keys, got %d", maxMultisigKeys, len(keys))
+	}
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("the threshold must be between 1 and %d", len(keys))
+	}
+
+	account := &MultisigAccount{
+		Name:      name,
+		Threshold: threshold,
+		Type:      scriptType,
+		Keys:      keys,
+		OwnKey:    own.String(),
+	}
+	err = walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		bucket, err := multisigBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(name)) != nil {
+			return ErrMultisigExists
+		}
+		return putMultisigAccount(bucket, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// MultisigAccounts returns the multisig accounts sorted by name.
+func (wa *WalletAccess) MultisigAccounts() ([]*MultisigAccount, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	var accounts []*MultisigAccount
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		meta := tx.ReadBucket(metaBucketKey)
+		if meta == nil {
+			return nil
+		}
+		bucket := meta.NestedReadBucket(multisigKey)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			account := &MultisigAccount{}
+			if err := json.Unmarshal(v, account); err != nil {
+				return fmt.Errorf("invalid multisig account %s: %w", k, err)
+			}
+			accounts = append(accounts, account)
+			return nil
+		})
+	})
+	return accounts, err
+}
+
+// MultisigAccount returns a multisig account by name.
+func (wa *WalletAccess) MultisigAccount(name string) (*MultisigAccount, error) {
+	accounts, err := wa.MultisigAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, nil
+		}
+	}
+	return nil, ErrMultisigNotFound
+}
+
+// NewMultisigAddress hands out the next address of a branch of a multisig
+// account.
+func (wa *WalletAccess) NewMultisigAddress(name string, branch uint32) (chainutil.Address, error) {
+	account, err := wa.MultisigAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	count := account.count(branch)
+	ma, err := account.address(branch, *count, wa.params.Network)
+	if err != nil {
+		return nil, err
+	}
+	*count++
+
+	if err := wa.saveMultisigAccount(account); err != nil {
+		return nil, err
+	}
+	return chainutil.DecodeAddress(ma.Address, wa.params.Network)
+}
+
+func (wa *WalletAccess) saveMultisigAccount(account *MultisigAccount) error {
+	return walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		bucket, err := multisigBucket(tx)
+		if err != nil {
+			return err
+		}
+		return putMultisigAccount(bucket, account)
+	})
+}
+
+// RefreshMultisigAccounts fetches the history of the multisig accounts from
+// the Electrum server. Each branch is looked up until multisigLookahead unused
+// addresses follow the last used or handed out one.
+func (ws *WalletService) RefreshMultisigAccounts() error {
+	accounts, err := ws.MultisigAccounts()
+	if err != nil || len(accounts) == 0 {
+		return err
+	}
+
+	fetcher, err := ws.newHistoryFetcher()
+	if err != nil {
+		return err
+	}
+	defer fetcher.close()
+
+	for _, account := range accounts {
+		for _, branch := range []uint32{waddrmgr.ExternalBranch, waddrmgr.InternalBranch} {
+			count := account.count(branch)
+			for index := uint32(0); index < *count+multisigLookahead; index++ {
+				ma, err := account.address(branch, index, ws.params.Network)
+				if err != nil {
+					return err
+				}
+				if err := fetcher.refresh(&ma.WatchedAddress); err != nil {
+					return err
+				}
+				if len(ma.History) > 0 {
+					*count = max(*count, index+1)
+				}
+			}
+		}
+
+		// Only the addresses in use are kept, the lookahead is derived
+		// again on each refresh.
+		addrs := account.Addresses[:0]
+		for _, ma := range account.Addresses {
+			if ma.Index < *account.count(ma.Branch) {
+				addrs = append(addrs, ma)
+			}
+		}
+		account.Addresses = addrs
+		sort.Slice(addrs, func(i, j int) bool {
+			if addrs[i].Branch != addrs[j].Branch {
+				return addrs[i].Branch < addrs[j].Branch
+			}
+			return addrs[i].Index < addrs[j].Index
+		})
+
+		if err := ws.saveMultisigAccount(account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multisigBucket returns the bucket of the multisig accounts, creating it if
+// needed.
+func multisigBucket(tx walletdb.ReadWriteTx) (walletdb.ReadWriteBucket, error) {
+	meta, err := tx.CreateTopLevelBucket(metaBucketKey)
+	if err != nil {
+		return nil, err
+	}
+	return meta.CreateBucketIfNotExists(multisigKey)
+}
+
+func putMultisigAccount(bucket walletdb.ReadWriteBucket, account *MultisigAccount) error {
+	value, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(account.Name), value)
+}
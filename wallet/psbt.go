@@ -0,0 +1,455 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg/chainhash"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/psbt"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/chain/electrum"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+)
+
+var (
+	// multisigDust is the smallest change output kept, smaller change is
+	// left to the fee.
+	multisigDust chainutil.Amount = 1000
+
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrPSBTMismatch      = errors.New("the PSBTs spend different transactions")
+)
+
+// inputSize returns the virtual size of an input spending a multisig
+// account address, once signed.
+func (m *MultisigAccount) inputSize() int64 {
+	// The script pushes the threshold, the keys, the key count and
+	// OP_CHECKMULTISIG.
+	script := int64(1 + 34*len(m.Keys) + 1 + 1)
+	sigs := int64(m.Threshold) * (1 + 72)
+	outpoint := int64(32 + 4 + 4)
+
+	if m.Type == MultisigP2SH {
+		// OP_0, the signatures and the redeem script push.
+		scriptSig := 1 + sigs + 3 + script
+		return outpoint + 3 + scriptSig
+	}
+
+	// The item count, the empty item, the signatures and the script.
+	witness := 1 + 1 + sigs + 3 + script
+	return outpoint + 1 + (witness+3)/4
+}
+
+// FundMultisig builds a PSBT paying outputs from the coins of a multisig
+// account, fetched from the Electrum server. The fee rate is in loki per
+// virtual byte, estimated by the server when zero. Change goes to a new
+// address of the internal branch. The PSBT carries the scripts every cosigner
+// needs to sign. The multisig accounts are refreshed first so that coins
+// received on lookahead addresses are found.
+func (ws *WalletService) FundMultisig(name string, outputs []*wire.TxOut, feeRate chainutil.Amount) (*psbt.Packet, error) {
+	if _, err := ws.MultisigAccount(name); err != nil {
+		return nil, err
+	}
+	if err := ws.RefreshMultisigAccounts(); err != nil {
+		return nil, fmt.Errorf("unable to refresh multisig accounts: %v", err)
+	}
+	account, err := ws.MultisigAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher, err := ws.newHistoryFetcher()
+	if err != nil {
+		return nil, err
+	}
+	defer fetcher.close()
+
+	if feeRate <= 0 {
+		estimate, err := fetcher.client.GetFee(fetcher.ctx, 6)
+		if err != nil {
+			return nil, fmt.Errorf("unable to estimate fee: %v", err)
+		}
+		perKB, err := chainutil.NewAmount(float64(estimate))
+		if err != nil {
+			return nil, err
+		}
+		feeRate = max(perKB/1000, 1)
+	}
+
+	var coins []multisigCoin
+	for _, ma := range account.Addresses {
+		scripthash, err := electrum.AddressToElectrumScriptHash(ma.Address, ws.params.Network)
+		if err != nil {
+			return nil, err
+		}
+		unspent, err := fetcher.client.ListUnspent(fetcher.ctx, scripthash)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list coins of %s: %v", ma.Address, err)
+		}
+		for _, u := range unspent {
+			hash, err := chainhash.NewHashFromStr(u.Hash)
+			if err != nil {
+				return nil, err
+			}
+			coins = append(coins, multisigCoin{
+				outpoint: wire.OutPoint{Hash: *hash, Index: u.Position},
+				value:    chainutil.Amount(u.Value),
+				branch:   ma.Branch,
+				index:    ma.Index,
+			})
+		}
+	}
+
+	return ws.multisigPSBT(account, coins, outputs, feeRate, fetcher.fetchTx)
+}
+
+// multisigCoin is an unspent output of an address of a multisig account.
+type multisigCoin struct {
+	outpoint wire.OutPoint
+	value    chainutil.Amount
+	branch   uint32
+	index    uint32
+}
+
+// multisigPSBT selects coins of a multisig account, largest first, to pay
+// outputs and builds the PSBT spending them. fetchTx returns the transaction
+// of a coin by id.
+func (wa *WalletAccess) multisigPSBT(account *MultisigAccount, coins []multisigCoin, outputs []*wire.TxOut,
+	feeRate chainutil.Amount, fetchTx func(txid string) (*wire.MsgTx, error)) (*psbt.Packet, error) {
+
+	sort.Slice(coins, func(i, j int) bool { return coins[i].value > coins[j].value })
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	var target chainutil.Amount
+	size := int64(10 + 1)
+	for _, out := range outputs {
+		tx.AddTxOut(out)
+		target += chainutil.Amount(out.Value)
+		size += int64(8 + 1 + len(out.PkScript))
+	}
+
+	// The change output is counted from the start, it is dropped when too
+	// small.
+	changeScript, changeAddr, err := account.Script(waddrmgr.InternalBranch, account.InternalCount, wa.params.Network)
+	if err != nil {
+		return nil, err
+	}
+	changePkScript, err := txscript.PayToAddrScript(changeAddr)
+	if err != nil {
+		return nil, err
+	}
+	size += int64(8 + 1 + len(changePkScript))
+
+	var selected []multisigCoin
+	var total chainutil.Amount
+	for _, c := range coins {
+		if total >= target+feeRate*chainutil.Amount(size) {
+			break
+		}
+		selected = append(selected, c)
+		total += c.value
+		size += account.inputSize()
+	}
+	fee := feeRate * chainutil.Amount(size)
+	if total < target+fee {
+		return nil, fmt.Errorf("%w: %v available, %v needed", ErrInsufficientFunds, total, target+fee)
+	}
+
+	for _, c := range selected {
+		tx.AddTxIn(wire.NewTxIn(&c.outpoint, nil, nil))
+	}
+	change := total - target - fee
+	hasChange := change >= multisigDust
+	if hasChange {
+		tx.AddTxOut(wire.NewTxOut(int64(change), changePkScript))
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range selected {
+		script, _, err := account.Script(c.branch, c.index, wa.params.Network)
+		if err != nil {
+			return nil, err
+		}
+		prevTx, err := fetchTx(c.outpoint.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		if account.Type == MultisigP2SH {
+			err = updater.AddInNonWitnessUtxo(prevTx, i)
+			if err == nil {
+				err = updater.AddInRedeemScript(script, i)
+			}
+		} else {
+			err = updater.AddInWitnessUtxo(prevTx.TxOut[c.outpoint.Index], i)
+			if err == nil {
+				err = updater.AddInWitnessScript(script, i)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := updater.AddInSighashType(txscript.SigHashAll, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasChange {
+		last := len(tx.TxOut) - 1
+		if account.Type == MultisigP2SH {
+			err = updater.AddOutRedeemScript(changeScript, last)
+		} else {
+			err = updater.AddOutWitnessScript(changeScript, last)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := wa.NewMultisigAddress(account.Name, waddrmgr.InternalBranch); err != nil {
+			return nil, err
+		}
+	}
+
+	return packet, nil
+}
+
+// SignMultisigPSBT adds the signatures of the wallet to the inputs of a PSBT
+// spending its multisig accounts, and returns how many inputs it signed. The
+// wallet must be unlocked.
+func (wa *WalletAccess) SignMultisigPSBT(packet *psbt.Packet) (int, error) {
+	props, err := wa.multisigKeyAccount()
+	if err != nil {
+		return 0, err
+	}
+	if props.AccountPrivKey == nil {
+		return 0, fmt.Errorf("the multisig key is not available, is the wallet unlocked?")
+	}
+
+	accounts, err := wa.MultisigAccounts()
+	if err != nil {
+		return 0, err
+	}
+
+	// Scripts are matched against the addresses in use of the accounts the
+	// wallet is a cosigner of.
+	type keyPath struct{ branch, index uint32 }
+	paths := make(map[string]keyPath)
+	for _, account := range accounts {
+		if account.OwnKey != props.AccountPubKey.String() {
+			continue
+		}
+		for _, branch := range []uint32{waddrmgr.ExternalBranch, waddrmgr.InternalBranch} {
+			for index := uint32(0); index < *account.count(branch)+multisigLookahead; index++ {
+				script, _, err := account.Script(branch, index, wa.params.Network)
+				if err != nil {
+					return 0, err
+				}
+				paths[hex.EncodeToString(script)] = keyPath{branch, index}
+			}
+		}
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return 0, err
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range packet.Inputs {
+		switch {
+		case in.WitnessUtxo != nil:
+			prevOuts.AddPrevOut(packet.UnsignedTx.TxIn[i].PreviousOutPoint, in.WitnessUtxo)
+		case in.NonWitnessUtxo != nil:
+			outpoint := packet.UnsignedTx.TxIn[i].PreviousOutPoint
+			if int(outpoint.Index) < len(in.NonWitnessUtxo.TxOut) {
+				prevOuts.AddPrevOut(outpoint, in.NonWitnessUtxo.TxOut[outpoint.Index])
+			}
+		}
+	}
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, prevOuts)
+
+	signed := 0
+	for i, in := range packet.Inputs {
+		script := in.WitnessScript
+		if script == nil {
+			script = in.RedeemScript
+		}
+		path, ok := paths[hex.EncodeToString(script)]
+		if !ok {
+			continue
+		}
+		if _, required, err := txscript.CalcMultiSigStats(script); err == nil && len(in.PartialSigs) >= required {
+			continue
+		}
+
+		key, err := props.AccountPrivKey.Derive(path.branch)
+		if err == nil {
+			key, err = key.Derive(path.index)
+		}
+		if err != nil {
+			return signed, err
+		}
+		privKey, err := key.ECPrivKey()
+		if err != nil {
+			return signed, err
+		}
+		pubKey := privKey.PubKey().SerializeCompressed()
+
+		alreadySigned := false
+		for _, sig := range in.PartialSigs {
+			alreadySigned = alreadySigned || bytes.Equal(sig.PubKey, pubKey)
+		}
+		if alreadySigned {
+			continue
+		}
+
+		var sig []byte
+		if in.WitnessScript != nil {
+			if in.WitnessUtxo == nil {
+				return signed, fmt.Errorf("input %d misses the output it spends", i)
+			}
+			sig, err = txscript.RawTxInWitnessSignature(packet.UnsignedTx, sigHashes, i,
+				in.WitnessUtxo.Value, in.WitnessScript, txscript.SigHashAll, privKey)
+		} else {
+			sig, err = txscript.RawTxInSignature(packet.UnsignedTx, i, in.RedeemScript, txscript.SigHashAll, privKey)
+		}
+		if err != nil {
+			return signed, err
+		}
+
+		outcome, err := updater.Sign(i, sig, pubKey, nil, nil)
+		if err != nil {
+			return signed, fmt.Errorf("unable to sign input %d: %v", i, err)
+		}
+		if outcome != psbt.SignSuccesful {
+			return signed, fmt.Errorf("unable to sign input %d: outcome %d", i, outcome)
+		}
+		signed++
+	}
+	return signed, nil
+}
+
+// CombinePSBTs merges the signatures of PSBTs spending the same transaction.
+func CombinePSBTs(packets []*psbt.Packet) (*psbt.Packet, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("no PSBT to combine")
+	}
+
+	combined := packets[0]
+	txid := combined.UnsignedTx.TxHash()
+	for _, packet := range packets[1:] {
+		if packet.UnsignedTx.TxHash() != txid {
+			return nil, ErrPSBTMismatch
+		}
+		for i, in := range packet.Inputs {
+			target := &combined.Inputs[i]
+			for _, sig := range in.PartialSigs {
+				found := false
+				for _, have := range target.PartialSigs {
+					if bytes.Equal(have.PubKey, sig.PubKey) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					target.PartialSigs = append(target.PartialSigs, sig)
+				}
+			}
+			if target.WitnessUtxo == nil {
+				target.WitnessUtxo = in.WitnessUtxo
+			}
+			if target.NonWitnessUtxo == nil {
+				target.NonWitnessUtxo = in.NonWitnessUtxo
+			}
+			if target.WitnessScript == nil {
+				target.WitnessScript = in.WitnessScript
+			}
+			if target.RedeemScript == nil {
+				target.RedeemScript = in.RedeemScript
+			}
+			if target.FinalScriptSig == nil && target.FinalScriptWitness == nil {
+				target.FinalScriptSig = in.FinalScriptSig
+				target.FinalScriptWitness = in.FinalScriptWitness
+			}
+		}
+	}
+	return combined, nil
+}
+
+// FinalizePSBT builds the final scripts of a fully signed PSBT and returns the
+// transaction ready to broadcast.
+func FinalizePSBT(packet *psbt.Packet) (*wire.MsgTx, error) {
+	for i, in := range packet.Inputs {
+		script := in.WitnessScript
+		if script == nil {
+			script = in.RedeemScript
+		}
+		if script == nil || in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+			continue
+		}
+		_, required, err := txscript.CalcMultiSigStats(script)
+		if err != nil {
+			continue
+		}
+		if len(in.PartialSigs) < required {
+			return nil, fmt.Errorf("input %d has %d of the %d signatures required", i, len(in.PartialSigs), required)
+		}
+
+		// OP_CHECKMULTISIG takes exactly the threshold of signatures, in the
+		// order of the keys of the script. Extra cosigners are dropped.
+		pubKeys, err := txscript.PushedData(script)
+		if err != nil {
+			return nil, err
+		}
+		sigs := make([]*psbt.PartialSig, 0, required)
+		for _, pubKey := range pubKeys {
+			for _, sig := range in.PartialSigs {
+				if len(sigs) < required && bytes.Equal(sig.PubKey, pubKey) {
+					sigs = append(sigs, sig)
+					break
+				}
+			}
+		}
+		packet.Inputs[i].PartialSigs = sigs
+	}
+
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return nil, fmt.Errorf("the PSBT misses signatures: %v", err)
+	}
+	return psbt.Extract(packet)
+}
+
+// BroadcastTransaction sends a transaction to the network through the
+// Electrum server and returns its id.
+func (ws *WalletService) BroadcastTransaction(tx *wire.MsgTx) (string, error) {
+	if !ws.isOpened {
+		return "", wallet.ErrNotLoaded
+	}
+
+	fetcher, err := ws.newHistoryFetcher()
+	if err != nil {
+		return "", err
+	}
+	defer fetcher.close()
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return fetcher.client.BroadcastTransaction(fetcher.ctx, hex.EncodeToString(buf.Bytes()))
+}
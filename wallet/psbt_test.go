@@ -0,0 +1,236 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chaincfg/chainhash"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/go-flokicoin/chainutil/psbt"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/waddrmgr"
+	_ "github.com/flokiorg/walletd/walletdb/bdb"
+)
+
+// newMultisigWallet restores a wallet from a seed made of one repeated byte
+// and gives it a multisig key. The wallet is left unlocked.
+func newMultisigWallet(t *testing.T, seedByte byte) *WalletAccess {
+	t.Helper()
+	wa := New(&WalletParams{
+		Network:        &chaincfg.MainNetParams,
+		Path:           t.TempDir(),
+		Timeout:        10 * time.Second,
+		PublicPassword: "public",
+		AddressScope:   waddrmgr.KeyScopeBIP0044,
+		AccountID:      1,
+	})
+	seed := bytes.Repeat([]byte{seedByte}, hdkeychain.RecommendedSeedLen)
+	if err := wa.RestoreWallet(seed, []byte("private"), "test", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		wa.Lock()
+		wa.CloseWallet()
+	})
+	if err := wa.Unlock([]byte("private"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wa.CreateMultisigKey(); err != nil {
+		t.Fatal(err)
+	}
+	return wa
+}
+
+// copyPSBT returns a deep copy of a PSBT, as handed to a cosigner.
+func copyPSBT(t *testing.T, packet *psbt.Packet) *psbt.Packet {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := packet.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	packet, err := psbt.NewFromRawBytes(&buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packet
+}
+
+func TestMultisigRoundTrip(t *testing.T) {
+	const branch, index = 0, 7
+	const feeRate chainutil.Amount = 10
+
+	wallets := make([]*WalletAccess, 3)
+	xpubs := make([]string, len(wallets))
+	for i := range wallets {
+		wallets[i] = newMultisigWallet(t, byte(i+1))
+		xpub, err := wallets[i].MultisigXpub()
+		if err != nil {
+			t.Fatal(err)
+		}
+		xpubs[i] = xpub.String()
+	}
+
+	for _, scriptType := range []string{MultisigP2WSH, MultisigP2SH} {
+		t.Run(scriptType, func(t *testing.T) {
+			// Every cosigner creates the account from the keys of the
+			// others.
+			accounts := make([]*MultisigAccount, len(wallets))
+			for i, wa := range wallets {
+				var cosigners []string
+				for j, xpub := range xpubs {
+					if j != i {
+						cosigners = append(cosigners, xpub)
+					}
+				}
+				account, err := wa.CreateMultisigAccount("vault-"+scriptType, 2, scriptType, cosigners)
+				if err != nil {
+					t.Fatal(err)
+				}
+				accounts[i] = account
+			}
+			account := accounts[0]
+
+			script, addr, err := account.Script(branch, index, &chaincfg.MainNetParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, other := range accounts[1:] {
+				otherScript, _, err := other.Script(branch, index, &chaincfg.MainNetParams)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(script, otherScript) {
+					t.Fatalf("cosigners derive different scripts")
+				}
+			}
+
+			// The script must be a sorted 2-of-3 paying to an address of
+			// the account type.
+			pubKeys, required, err := txscript.CalcMultiSigStats(script)
+			if err != nil || pubKeys != 3 || required != 2 {
+				t.Fatalf("got a %d-of-%d script, %v", required, pubKeys, err)
+			}
+			pushed, err := txscript.PushedData(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i < len(pushed); i++ {
+				if bytes.Compare(pushed[i-1], pushed[i]) >= 0 {
+					t.Fatalf("keys of the script are not sorted")
+				}
+			}
+			switch addr.(type) {
+			case *chainutil.AddressWitnessScriptHash:
+				if scriptType != MultisigP2WSH {
+					t.Fatalf("got a P2WSH address for a %s account", scriptType)
+				}
+			case *chainutil.AddressScriptHash:
+				if scriptType != MultisigP2SH {
+					t.Fatalf("got a P2SH address for a %s account", scriptType)
+				}
+			default:
+				t.Fatalf("unexpected address type %T", addr)
+			}
+
+			pkScript, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prevTx := wire.NewMsgTx(wire.TxVersion)
+			prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+			prevTx.AddTxOut(wire.NewTxOut(100000000, pkScript))
+			coin := multisigCoin{
+				outpoint: wire.OutPoint{Hash: prevTx.TxHash()},
+				value:    chainutil.Amount(prevTx.TxOut[0].Value),
+				branch:   branch,
+				index:    index,
+			}
+			fetchTx := func(txid string) (*wire.MsgTx, error) {
+				if txid != prevTx.TxHash().String() {
+					t.Fatalf("unexpected transaction %s", txid)
+				}
+				return prevTx, nil
+			}
+
+			outputs := []*wire.TxOut{wire.NewTxOut(50000000, pkScript)}
+			packet, err := wallets[0].multisigPSBT(account, []multisigCoin{coin}, outputs, feeRate, fetchTx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(packet.UnsignedTx.TxOut); n != 2 {
+				t.Fatalf("got %d outputs, want the payment and the change", n)
+			}
+
+			// A single signature is not enough.
+			partial := copyPSBT(t, packet)
+			if signed, err := wallets[0].SignMultisigPSBT(partial); err != nil || signed != 1 {
+				t.Fatalf("signed %d inputs, %v", signed, err)
+			}
+			if _, err := FinalizePSBT(copyPSBT(t, partial)); err == nil {
+				t.Fatalf("finalized a PSBT with one of two signatures")
+			}
+
+			// Once complete, an input is not signed again.
+			complete := copyPSBT(t, partial)
+			if _, err := wallets[1].SignMultisigPSBT(complete); err != nil {
+				t.Fatal(err)
+			}
+			if signed, err := wallets[2].SignMultisigPSBT(complete); err != nil || signed != 0 {
+				t.Fatalf("signed %d complete inputs, %v", signed, err)
+			}
+
+			// Every cosigner signs on their own, more than the threshold.
+			var signed []*psbt.Packet
+			for _, wa := range wallets {
+				own := copyPSBT(t, packet)
+				if n, err := wa.SignMultisigPSBT(own); err != nil || n != 1 {
+					t.Fatalf("signed %d inputs, %v", n, err)
+				}
+				signed = append(signed, own)
+			}
+			combined, err := CombinePSBTs(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(combined.Inputs[0].PartialSigs); n != 3 {
+				t.Fatalf("combined PSBT holds %d signatures, want 3", n)
+			}
+			fee, err := combined.GetTxFee()
+			if err != nil {
+				t.Fatal(err)
+			}
+			final, err := FinalizePSBT(combined)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, prevTx.TxOut[0].Value)
+			engine, err := txscript.NewEngine(pkScript, final, 0, txscript.StandardVerifyFlags,
+				nil, txscript.NewTxSigHashes(final, prevOuts), prevTx.TxOut[0].Value, prevOuts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.Execute(); err != nil {
+				t.Fatalf("finalized transaction does not verify: %v", err)
+			}
+
+			// The fee must cover the signed transaction without
+			// overpaying much, the estimate counting signatures of the
+			// largest size.
+			estimate := int64(fee / feeRate)
+			weight := int64(final.SerializeSizeStripped()*3 + final.SerializeSize())
+			vsize := (weight + 3) / 4
+			if estimate < vsize || estimate > vsize+10 {
+				t.Fatalf("paid for %d vbytes for a transaction of %d", estimate, vsize)
+			}
+		})
+	}
+}
@@ -0,0 +1,495 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chaincfg/chainhash"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/chain/electrum"
+	"github.com/flokiorg/walletd/waddrmgr"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+	"github.com/flokiorg/walletd/wtxmgr"
+)
+
+// wtxmgrNamespaceKey is the bucket of the transaction store in the wallet
+// database, as named by the wallet package.
+var wtxmgrNamespaceKey = []byte("wtxmgr")
+
+// historyTx is a wallet transaction fetched from the Electrum server.
+type historyTx struct {
+	msgTx *wire.MsgTx
+	block *wtxmgr.BlockMeta
+}
+
+// BranchScan counts the addresses of a branch looked up during a scan.
+type BranchScan struct {
+	// Scanned is the number of addresses looked up so far.
+	Scanned uint32
+
+	// Total is the number of addresses expected to be looked up, it grows
+	// as used addresses push the gap limit further.
+	Total uint32
+
+	// Used is the number of addresses with history.
+	Used uint32
+
+	// Funds is the balance held by the used addresses.
+	Funds chainutil.Amount
+}
+
+// ScanStats reports the progress and outcome of a history scan.
+type ScanStats struct {
+	// External and Internal cover the receive and change branches of the
+	// opened account.
+	External BranchScan
+	Internal BranchScan
+
+	// Other covers imported addresses and those of other accounts.
+	Other BranchScan
+
+	// Transactions is the number of transactions restored, set once the
+	// scan completes.
+	Transactions int
+}
+
+// Branch returns the counters of an account branch.
+func (s *ScanStats) Branch(branch uint32) *BranchScan {
+	if branch == waddrmgr.InternalBranch {
+		return &s.Internal
+	}
+	return &s.External
+}
+
+// RescanHistory drops the wallet transactions confirmed from fromHeight on and
+// fetches the history of every known address again from the Electrum server.
+// A negative height rescans from the birthday block, dropping the whole
+// transaction history. The branches of the opened account are extended until
+// the gap limit is reached past their last used address. Addresses, accounts,
+// imported keys and transaction labels are kept, and the wallet ends synced
+// to the chain tip. The progress callback is called after each address.
+func (ws *WalletService) RescanHistory(fromHeight int32, progress func(*ScanStats)) (*ScanStats, error) {
+	if !ws.isOpened || ws.account == nil {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := electrum.NewClient(ws.params.ElectrumServer, nil)
+	startCtx, startCancel := context.WithTimeout(ctx, time.Second*10)
+	defer startCancel()
+	if err := client.Start(startCtx); err != nil {
+		return nil, err
+	}
+	defer client.Shutdown()
+
+	full := fromHeight < 0
+	if full {
+		fromHeight = 0
+		if _, birthday, err := ws.Birthday(); err == nil && birthday != nil {
+			fromHeight = birthday.Height
+		}
+	}
+
+	_, tip, err := client.GetBestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fromHeight > tip {
+		return nil, fmt.Errorf("height %d is above the chain tip %d", fromHeight, tip)
+	}
+
+	// The wallet is marked synced up to the block before the rescan start.
+	startHeight := max(fromHeight-1, 0)
+	startHash, startHeader, err := client.GetBlockHash(ctx, uint32(startHeight))
+	if err != nil {
+		return nil, err
+	}
+	start := &waddrmgr.BlockStamp{Height: startHeight, Hash: *startHash, Timestamp: startHeader.Timestamp}
+
+	manager, err := ws.Manager.FetchScopedKeyManager(ws.params.AddressScope)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []chainutil.Address
+	paths := make(map[string]waddrmgr.DerivationPath)
+	err = walletdb.View(ws.Database(), func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		err := ws.Manager.ForEachActiveAddress(ns, func(addr chainutil.Address) error {
+			addrs = append(addrs, addr)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return manager.ForEachAccountAddress(ns, ws.account.AccountNumber, func(ma waddrmgr.ManagedAddress) error {
+			if pka, ok := ma.(waddrmgr.ManagedPubKeyAddress); ok {
+				if _, path, ok := pka.DerivationInfo(); ok {
+					paths[ma.Address().EncodeAddress()] = path
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if full {
+		if err := wallet.DropTransactionHistory(ws.Database(), true); err != nil {
+			return nil, err
+		}
+	}
+	err = walletdb.Update(ws.Database(), func(tx walletdb.ReadWriteTx) error {
+		if !full {
+			if err := ws.TxStore.Rollback(tx.ReadWriteBucket(wtxmgrNamespaceKey), fromHeight); err != nil {
+				return err
+			}
+		}
+		return ws.Manager.SetSyncedTo(tx.ReadWriteBucket(waddrmgrNamespaceKey), start)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := ws.AccountProperties(ws.params.AddressScope, ws.account.AccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	gapLimit := ws.GapLimit()
+	keyCount := map[uint32]uint32{
+		waddrmgr.ExternalBranch: props.ExternalKeyCount,
+		waddrmgr.InternalBranch: props.InternalKeyCount,
+	}
+
+	// next is the index past the last used address of each branch.
+	next := make(map[uint32]uint32)
+	stats := &ScanStats{}
+	stats.External.Total = max(keyCount[waddrmgr.ExternalBranch], gapLimit)
+	stats.Internal.Total = max(keyCount[waddrmgr.InternalBranch], gapLimit)
+
+	heights := make(map[chainhash.Hash]int32)
+	scan := func(addr chainutil.Address, branch *uint32, index uint32) (bool, error) {
+		funds, used, err := fetchAddressHistory(ctx, client, addr, ws.params.Network, fromHeight, heights)
+		if err != nil {
+			return false, err
+		}
+
+		bs := &stats.Other
+		if branch != nil {
+			bs = stats.Branch(*branch)
+			if used {
+				next[*branch] = max(next[*branch], index+1)
+			}
+			bs.Total = max(keyCount[*branch], next[*branch]+gapLimit)
+		}
+		bs.Scanned++
+		if used {
+			bs.Used++
+			bs.Funds += funds
+		}
+
+		if progress != nil {
+			progress(stats)
+		}
+		return used, nil
+	}
+
+	for _, addr := range addrs {
+		var branch *uint32
+		path, ok := paths[addr.EncodeAddress()]
+		if ok {
+			branch = &path.Branch
+		}
+		if _, err := scan(addr, branch, path.Index); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, branch := range []uint32{waddrmgr.ExternalBranch, waddrmgr.InternalBranch} {
+		branchKey, err := ws.account.AccountPubKey.Derive(branch)
+		if err != nil {
+			return nil, err
+		}
+		for index := keyCount[branch]; index < next[branch]+gapLimit; index++ {
+			key, err := branchKey.Derive(index)
+			if err != nil {
+				return nil, err
+			}
+			addr, err := key.Address(ws.params.Network)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := scan(addr, &branch, index); err != nil {
+				return nil, err
+			}
+		}
+
+		if count := keyCount[branch]; next[branch] > count {
+			if branch == waddrmgr.ExternalBranch {
+				_, err = ws.NewAddressRPCLess(ws.account.AccountNumber, ws.params.AddressScope, next[branch]-count)
+			} else {
+				_, err = ws.NewChangeAddressRPCLess(ws.account.AccountNumber, ws.params.AddressScope, next[branch]-count)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	txs, err := ws.fetchHistoryTxs(ctx, client, heights)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, htx := range txs {
+		if err := ws.insertHistoryTx(htx); err != nil {
+			return nil, err
+		}
+	}
+	stats.Transactions = len(txs)
+
+	// The history is complete up to the tip, there is nothing left for the
+	// next sync to scan.
+	tipHash, tipHeader, err := client.GetBlockHash(ctx, uint32(tip))
+	if err != nil {
+		return nil, err
+	}
+	err = walletdb.Update(ws.Database(), func(tx walletdb.ReadWriteTx) error {
+		return ws.Manager.SetSyncedTo(tx.ReadWriteBucket(waddrmgrNamespaceKey), &waddrmgr.BlockStamp{
+			Height:    tip,
+			Hash:      *tipHash,
+			Timestamp: tipHeader.Timestamp,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ScanAddresses fetches from the Electrum server the history of addresses
+// already known to the wallet, such as freshly imported keys, confirmed from
+// fromHeight on. Unlike RescanHistory nothing is dropped and the sync state
+// of the wallet is left as is.
+func (ws *WalletService) ScanAddresses(addrs []chainutil.Address, fromHeight int32) (*ScanStats, error) {
+	if !ws.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := electrum.NewClient(ws.params.ElectrumServer, nil)
+	startCtx, startCancel := context.WithTimeout(ctx, time.Second*10)
+	defer startCancel()
+	if err := client.Start(startCtx); err != nil {
+		return nil, err
+	}
+	defer client.Shutdown()
+
+	stats := &ScanStats{}
+	heights := make(map[chainhash.Hash]int32)
+	for _, addr := range addrs {
+		funds, used, err := fetchAddressHistory(ctx, client, addr, ws.params.Network, fromHeight, heights)
+		if err != nil {
+			return nil, err
+		}
+		stats.Other.Scanned++
+		if used {
+			stats.Other.Used++
+			stats.Other.Funds += funds
+		}
+	}
+	stats.Other.Total = stats.Other.Scanned
+
+	txs, err := ws.fetchHistoryTxs(ctx, client, heights)
+	if err != nil {
+		return nil, err
+	}
+	for _, htx := range txs {
+		if err := ws.insertHistoryTx(htx); err != nil {
+			return nil, err
+		}
+	}
+	stats.Transactions = len(txs)
+
+	return stats, nil
+}
+
+// fetchAddressHistory adds the transactions of an address confirmed from
+// fromHeight on, or still unconfirmed, to heights. It returns the balance of
+// the address and whether it has any history at all.
+func fetchAddressHistory(ctx context.Context, client *electrum.Client, addr chainutil.Address, network *chaincfg.Params, fromHeight int32, heights map[chainhash.Hash]int32) (chainutil.Amount, bool, error) {
+	scripthash, err := electrum.AddressToElectrumScriptHash(addr.EncodeAddress(), network)
+	if err != nil {
+		return 0, false, err
+	}
+
+	history, err := client.GetHistory(ctx, scripthash)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to fetch history of %s: %v", addr, err)
+	}
+	if len(history) == 0 {
+		return 0, false, nil
+	}
+
+	for _, h := range history {
+		// Unconfirmed transactions are reported with a height of 0 or -1,
+		// they are kept whatever the rescan start.
+		if h.Height > 0 && h.Height < fromHeight {
+			continue
+		}
+		hash, err := chainhash.NewHashFromStr(h.Hash)
+		if err != nil {
+			return 0, false, err
+		}
+		heights[*hash] = h.Height
+	}
+
+	balance, err := client.GetBalance(ctx, scripthash)
+	if err != nil {
+		return 0, true, fmt.Errorf("unable to fetch balance of %s: %v", addr, err)
+	}
+
+	return chainutil.Amount(balance.Confirmed + balance.Unconfirmed), true, nil
+}
+
+// fetchHistoryTxs downloads the transactions and their blocks, and returns
+// them in chain order.
+func (ws *WalletService) fetchHistoryTxs(ctx context.Context, client *electrum.Client, heights map[chainhash.Hash]int32) ([]*historyTx, error) {
+	blocks := make(map[int32]*wtxmgr.BlockMeta)
+	txs := make([]*historyTx, 0, len(heights))
+
+	for hash, height := range heights {
+		raw, err := client.GetRawTransaction(ctx, hash.String())
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch tx %s: %v", hash, err)
+		}
+		txBytes, err := hex.DecodeString(raw)
+		if err != nil {
+			return nil, err
+		}
+		msgTx := &wire.MsgTx{}
+		if err := msgTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return nil, fmt.Errorf("unable to decode tx %s: %v", hash, err)
+		}
+
+		htx := &historyTx{msgTx: msgTx}
+		if height > 0 {
+			block, ok := blocks[height]
+			if !ok {
+				blockHash, header, err := client.GetBlockHash(ctx, uint32(height))
+				if err != nil {
+					return nil, err
+				}
+				block = &wtxmgr.BlockMeta{
+					Block: wtxmgr.Block{Hash: *blockHash, Height: height},
+					Time:  header.Timestamp,
+				}
+				blocks[height] = block
+			}
+			htx.block = block
+		}
+		txs = append(txs, htx)
+	}
+
+	sortHistoryTxs(txs)
+	return txs, nil
+}
+
+// sortHistoryTxs orders transactions by height, unconfirmed ones last, so
+// that outputs are credited before being spent. Within a block, parents are
+// moved before the transactions spending them.
+func sortHistoryTxs(txs []*historyTx) {
+	height := func(htx *historyTx) int32 {
+		if htx.block == nil {
+			return 1<<31 - 1
+		}
+		return htx.block.Height
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return height(txs[i]) < height(txs[j])
+	})
+
+	for i := 0; i < len(txs); i++ {
+		for j := i + 1; j < len(txs) && height(txs[j]) == height(txs[i]); j++ {
+			if spends(txs[i].msgTx, txs[j].msgTx.TxHash()) {
+				parent := txs[j]
+				copy(txs[i+1:j+1], txs[i:j])
+				txs[i] = parent
+				i--
+				break
+			}
+		}
+	}
+}
+
+// spends reports whether tx spends an output of the transaction with hash.
+func spends(tx *wire.MsgTx, hash chainhash.Hash) bool {
+	for _, in := range tx.TxIn {
+		if in.PreviousOutPoint.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// insertHistoryTx stores a transaction and credits its outputs paying to the
+// wallet, as the wallet does for transactions notified by the chain.
+func (ws *WalletService) insertHistoryTx(htx *historyTx) error {
+	received := time.Now()
+	if htx.block != nil {
+		received = htx.block.Time
+	}
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(htx.msgTx, received)
+	if err != nil {
+		return err
+	}
+
+	return walletdb.Update(ws.Database(), func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		txmgrNs := tx.ReadWriteBucket(wtxmgrNamespaceKey)
+
+		if err := ws.TxStore.InsertTx(txmgrNs, rec, htx.block); err != nil && !errors.Is(err, wtxmgr.ErrDuplicateTx) {
+			return err
+		}
+
+		for i, output := range htx.msgTx.TxOut {
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(output.PkScript, ws.params.Network)
+			if err != nil {
+				continue
+			}
+			for _, addr := range addrs {
+				ma, err := ws.Manager.Address(addrmgrNs, addr)
+				if err != nil {
+					continue
+				}
+				if err := ws.TxStore.AddCredit(txmgrNs, rec, htx.block, uint32(i), ma.Internal()); err != nil {
+					return err
+				}
+				if err := ws.Manager.MarkUsed(addrmgrNs, addr); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		return nil
+	})
+}
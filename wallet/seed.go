@@ -0,0 +1,140 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flokiorg/go-flokicoin/chainutil/hdkeychain"
+	"github.com/flokiorg/walletd/walletseed/bip39"
+	"github.com/flokiorg/walletd/walletseed/bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SeedFromEntropy derives the wallet seed from the mnemonic entropy.
+//
+// Without a BIP39 passphrase the entropy itself is the seed, which is how fcli
+// wallets have always been created. With a passphrase, even an empty one, the
+// seed is derived from the mnemonic sentence as specified by BIP39, so the
+// same words and passphrase restore the same addresses in other wallets.
+func SeedFromEntropy(entropy []byte, seedPass *string) ([]byte, error) {
+	if seedPass == nil {
+		return entropy, nil
+	}
+
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate mnemonic: %v", err)
+	}
+
+	// BIP39 requires both the sentence and the passphrase in NFKD form.
+	return bip39.NewSeed(norm.NFKD.String(mnemonic), norm.NFKD.String(*seedPass)), nil
+}
+
+// SeedFromHex decodes a hex encoded seed, as printed at wallet creation.
+func SeedFromHex(input string) ([]byte, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(input))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex seed: %v", err)
+	}
+	if len(seed) < hdkeychain.MinSeedBytes || len(seed) > hdkeychain.MaxSeedBytes {
+		return nil, hdkeychain.ErrInvalidSeedLen
+	}
+	return seed, nil
+}
+
+// MnemonicLanguages maps the language names accepted on the command line to
+// the BIP39 wordlists.
+var MnemonicLanguages = map[string][]string{
+	"english":             wordlists.English,
+	"spanish":             wordlists.Spanish,
+	"french":              wordlists.French,
+	"italian":             wordlists.Italian,
+	"japanese":            wordlists.Japanese,
+	"korean":              wordlists.Korean,
+	"czech":               wordlists.Czech,
+	"chinese-simplified":  wordlists.ChineseSimplified,
+	"chinese-traditional": wordlists.ChineseTraditional,
+}
+
+// SetMnemonicLanguage selects the wordlist used to encode and decode
+// mnemonics.
+func SetMnemonicLanguage(language string) error {
+	list, ok := MnemonicLanguages[language]
+	if !ok {
+		return fmt.Errorf("unknown mnemonic language %q", language)
+	}
+	bip39.SetWordList(list)
+	return nil
+}
+
+// SeedLenForWords returns the entropy length, in bytes, encoded by a mnemonic
+// of the given number of words.
+func SeedLenForWords(words int) (uint8, error) {
+	switch words {
+	case 12, 15, 18, 21, 24:
+		return uint8(words / 3 * 4), nil
+	}
+	return 0, fmt.Errorf("invalid word count %d, expected 12, 15, 18, 21 or 24", words)
+}
+
+// EntropyFromMnemonic decodes a mnemonic written in the given language. With
+// an empty language, every wordlist is tried and the mnemonic must decode in
+// exactly one way. The wordlist of the decoded language is left selected, and
+// its name returned.
+func EntropyFromMnemonic(mnemonic, language string) ([]byte, string, error) {
+	// Wordlists are stored in NFKD form, accents and kana included.
+	mnemonic = strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+
+	if language != "" {
+		if err := SetMnemonicLanguage(language); err != nil {
+			return nil, "", err
+		}
+		entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+		return entropy, language, err
+	}
+
+	var (
+		entropy  []byte
+		detected []string
+	)
+	for _, name := range sortedLanguages() {
+		bip39.SetWordList(MnemonicLanguages[name])
+		e, err := bip39.EntropyFromMnemonic(mnemonic)
+		if err != nil {
+			continue
+		}
+		// Simplified and traditional Chinese share part of their words, a
+		// mnemonic made of those only decodes the same in both.
+		if entropy != nil && !bytes.Equal(entropy, e) {
+			return nil, "", fmt.Errorf("mnemonic is valid in several languages (%s), select one with --language",
+				strings.Join(append(detected, name), ", "))
+		}
+		entropy = e
+		detected = append(detected, name)
+	}
+
+	if entropy == nil {
+		bip39.SetWordList(wordlists.English)
+		return nil, "", bip39.ErrInvalidMnemonic
+	}
+
+	bip39.SetWordList(MnemonicLanguages[detected[0]])
+	return entropy, detected[0], nil
+}
+
+// sortedLanguages returns the mnemonic language names in a stable order.
+func sortedLanguages() []string {
+	names := make([]string, 0, len(MnemonicLanguages))
+	for name := range MnemonicLanguages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
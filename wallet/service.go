@@ -34,6 +34,7 @@ type WalletService struct {
 	txNotif        chan *wallet.TransactionNotifications
 	spentNessNotif chan *wallet.SpentnessNotifications
 	healthNotif    chan error
+	notifications  chan interface{}
 }
 
 func NewWalletService(params *WalletParams) *WalletService {
@@ -41,10 +42,11 @@ func NewWalletService(params *WalletParams) *WalletService {
 		WalletAccess:   New(params),
 		params:         params,
 		stop:           make(chan struct{}),
-		accountNotif:   make(chan *wallet.AccountNotification),
-		txNotif:        make(chan *wallet.TransactionNotifications),
-		spentNessNotif: make(chan *wallet.SpentnessNotifications),
-		healthNotif:    make(chan error),
+		accountNotif:   make(chan *wallet.AccountNotification, 10),
+		txNotif:        make(chan *wallet.TransactionNotifications, 10),
+		spentNessNotif: make(chan *wallet.SpentnessNotifications, 10),
+		healthNotif:    make(chan error, 10),
+		notifications:  make(chan interface{}, 10),
 		synced:         new(int32),
 	}
 }
@@ -57,18 +59,22 @@ func (ws *WalletService) IsSynced() bool {
 	return atomic.LoadInt32(ws.synced) == 1
 }
 
-func (ws *WalletService) Synchronize() (err error) {
+func (ws *WalletService) Synchronize() (*waddrmgr.BlockStamp, error) {
 	return ws.synchronize(true)
 }
 
-func (ws *WalletService) synchronize(watch bool) (err error) {
+func (ws *WalletService) SynchronizeWatchless() (bestBlock *waddrmgr.BlockStamp, err error) {
+	return ws.synchronize(false)
+}
+
+func (ws *WalletService) synchronize(watch bool) (bestBlock *waddrmgr.BlockStamp, err error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
 	client := electrum.NewClient(ws.params.ElectrumServer, nil)
 	if err = client.Start(ctx); err != nil {
-		return err
+		return
 	}
 
 	defer func() {
@@ -79,11 +85,10 @@ func (ws *WalletService) synchronize(watch bool) (err error) {
 
 	chainClient := chain.NewElectrumClient(ws.params.Network, client)
 	if err = chainClient.Start(ctx); err != nil {
-		return err
+		return
 	}
 
 	ws.stopService()
-
 	if ws.IsOpened() {
 		ws.CloseWallet()
 	}
@@ -100,13 +105,56 @@ func (ws *WalletService) synchronize(watch bool) (err error) {
 	atomic.StoreInt32(ws.synced, 1)
 	if watch {
 		ws.watch()
+		ws.healthCheck()
+		ws.notificationsHandler()
 	}
 
 	ws.SynchronizeRPC(chainClient)
-	// ws.SetChainSynced(true)
+	ws.SetChainSynced(true)
 	ws.electrumClient = client
 
-	return nil
+	bestBlock, err = ws.CurrentBestBlock()
+	return
+}
+
+func (ws *WalletService) healthCheck() {
+	ws.wg.Add(1)
+
+	go func() {
+		defer ws.wg.Done()
+		for {
+			select {
+			case err := <-ws.electrumHealth:
+				ws.healthNotif <- err
+
+			case <-ws.stop:
+				return
+			}
+		}
+	}()
+
+}
+
+func (ws *WalletService) notificationsHandler() {
+	ws.wg.Add(1)
+
+	go func() {
+		defer ws.wg.Done()
+		for {
+			chainClient, ok := ws.Wallet.ChainClient().(*chain.ElectrumClient)
+			if !ok {
+				return
+			}
+			select {
+			case n := <-chainClient.ClientNotifications():
+				ws.notifications <- n
+
+			case <-ws.stop:
+				return
+			}
+		}
+	}()
+
 }
 
 func (ws *WalletService) watch() {
@@ -116,19 +164,13 @@ func (ws *WalletService) watch() {
 	txtNotif := ws.NtfnServer.TransactionNotifications()
 	spentNessNotif := ws.NtfnServer.AccountSpentnessNotifications(ws.account.AccountNumber)
 
-	time.AfterFunc(time.Second*5, func() {
-		ws.accountNotif <- &wallet.AccountNotification{
-			AccountNumber: ws.account.AccountNumber,
-		}
-	})
-
 	go func() {
 		defer ws.wg.Done()
 
 		for {
 			select {
 
-			case <-time.After(time.Second * 10):
+			case <-time.After(time.Second * 5):
 				ws.accountNotif <- &wallet.AccountNotification{
 					AccountNumber: ws.account.AccountNumber,
 				}
@@ -142,9 +184,6 @@ func (ws *WalletService) watch() {
 			case n := <-spentNessNotif.C:
 				ws.spentNessNotif <- n
 
-			case err := <-ws.electrumHealth:
-				ws.healthNotif <- err
-
 			case <-ws.stop:
 				return
 
@@ -154,23 +193,23 @@ func (ws *WalletService) watch() {
 
 }
 
-func (ws *WalletService) Watch() (<-chan *wallet.AccountNotification, <-chan *wallet.TransactionNotifications, <-chan *wallet.SpentnessNotifications, chan error) {
-	return ws.accountNotif, ws.txNotif, ws.spentNessNotif, ws.healthNotif
+func (ws *WalletService) Watch() (<-chan *wallet.AccountNotification, <-chan *wallet.TransactionNotifications, <-chan *wallet.SpentnessNotifications, chan interface{}, chan error) {
+	return ws.accountNotif, ws.txNotif, ws.spentNessNotif, ws.notifications, ws.healthNotif
 }
 
-func (ws *WalletService) Create(seedLen uint8, name, passphrase string) (hexData string, words []string, err error) {
+func (ws *WalletService) Create(seedLen uint8, name, passphrase string, seedPass *string) (hexData string, words []string, err error) {
 	defer func() {
 		if err != nil {
 			ws.DestroyWallet()
 		}
 	}()
-	var seed []byte
-	seed, err = ws.CreateWallet([]byte(passphrase), seedLen, name)
+	var entropy, seed []byte
+	entropy, seed, err = ws.CreateWallet([]byte(passphrase), seedLen, name, seedPass)
 	if err != nil {
 		return
 	}
 
-	hexData, words, err = ws.backupData(seed)
+	hexData, words, err = ws.backupData(entropy, seed)
 	return
 }
 
@@ -186,6 +225,39 @@ func (ws *WalletService) Balance() float64 {
 	return balance.Total.ToFLC()
 }
 
+func (ws *WalletService) CurrentWalletBlock() (*waddrmgr.BlockStamp, error) {
+	if !ws.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	ret, err := ws.Wallet.Accounts(ws.params.AddressScope)
+	if err != nil {
+		return nil, err
+	}
+	return &waddrmgr.BlockStamp{
+		Height: ret.CurrentBlockHeight,
+		Hash:   *ret.CurrentBlockHash,
+	}, nil
+}
+
+func (ws *WalletService) CurrentBestBlock() (*waddrmgr.BlockStamp, error) {
+	if atomic.LoadInt32(ws.synced) == 0 {
+		return nil, electrum.ErrServerShutdown
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	block, height, err := ws.electrumClient.GetBestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waddrmgr.BlockStamp{
+		Height: height,
+		Hash:   *block,
+	}, nil
+}
+
 func (ws *WalletService) GetLastAddress() (chainutil.Address, error) {
 	if !ws.isOpened || ws.account == nil {
 		return nil, wallet.ErrNotLoaded
@@ -256,121 +328,94 @@ func (ws *WalletService) FetchTransactions() ([]TransactionServiceResult, error)
 
 }
 
-func (ws *WalletService) RestoreByHex(input string, name, passphrase string) (hexData string, words []string, err error) {
-
-	defer func() {
-		if err != nil {
-			ws.DestroyWallet()
-		}
-	}()
-
-	var seed []byte
-	seed, err = hex.DecodeString(input)
+// RestoreByHex restores a wallet from the hex encoded seed printed at wallet
+// creation. The birthday is the earliest time the wallet may have been used,
+// see RestoreWallet.
+func (ws *WalletService) RestoreByHex(input string, name, passphrase string, birthday time.Time) error {
+	seed, err := SeedFromHex(input)
 	if err != nil {
-		return
+		return err
 	}
 
-	err = ws.RestoreWallet(seed, []byte(passphrase), name)
-	if err != nil {
-		return
+	if err := ws.RestoreWallet(seed, []byte(passphrase), name, birthday); err != nil {
+		ws.DestroyWallet()
+		return err
 	}
-
-	hexData, words, err = ws.backupData(seed)
-	return
+	return nil
 }
 
-func (ws *WalletService) RestoreByMnemonic(input []string, name, passphrase string) (hexData string, words []string, err error) {
-
-	defer func() {
-		if err != nil {
-			ws.DestroyWallet()
-		}
-	}()
-
-	var seed []byte
-	seed, err = bip39.EntropyFromMnemonic(strings.Join(input, " "))
-	if err != nil {
-		return
-	}
-
-	err = ws.RestoreWallet(seed, []byte(passphrase), name)
-	if err != nil {
-		return
+// HasHistory reports whether any of the first lookahead addresses of the
+// account branches has on-chain history. It is a cheap way to tell whether a
+// restored seed (and its passphrase) is the one actually in use.
+func (ws *WalletService) HasHistory(lookahead uint32) (bool, error) {
+	if !ws.isOpened || ws.account == nil {
+		return false, wallet.ErrNotLoaded
 	}
 
-	hexData, words, err = ws.backupData(seed)
-
-	return
-}
-
-func (ws *WalletService) Recover(counter chan<- uint32) error {
-
-	if !ws.isOpened {
-		return wallet.ErrNotLoaded
+	if ws.account.AccountPubKey == nil {
+		return false, fmt.Errorf("account %d can't derive", ws.account.AccountNumber)
 	}
 
-	if err := ws.synchronize(false); err != nil {
-		return err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
 
-	electrumClient, ok := ws.Wallet.ChainClient().(*chain.ElectrumClient)
-	if !ok {
-		return fmt.Errorf("recovering not supported !") // skip recovering
+	client := electrum.NewClient(ws.params.ElectrumServer, nil)
+	if err := client.Start(ctx); err != nil {
+		return false, err
 	}
+	defer client.Shutdown()
 
-	quit := make(chan struct{})
-	defer close(quit)
+	for _, branch := range []uint32{waddrmgr.ExternalBranch, waddrmgr.InternalBranch} {
+		branchKey, err := ws.account.AccountPubKey.Derive(branch)
+		if err != nil {
+			return false, err
+		}
 
-	go func() {
-		for {
-			select {
-			case notif := <-electrumClient.Notifications():
-				c, ok := notif.(uint32)
-				if !ok {
-					continue
-				}
+		for index := uint32(0); index < lookahead; index++ {
+			key, err := branchKey.Derive(index)
+			if err != nil {
+				return false, err
+			}
 
-				select {
-				case counter <- c:
-				default:
-				}
+			address, err := key.Address(ws.params.Network)
+			if err != nil {
+				return false, err
+			}
 
-			case <-quit:
-				return
+			scripthash, err := electrum.AddressToElectrumScriptHash(address.EncodeAddress(), ws.params.Network)
+			if err != nil {
+				return false, err
 			}
-		}
-	}()
 
-	externalAddrCount, internalAddrCount, err := electrumClient.Recover(ws.account, recoveryWindow)
-	if err != nil {
-		return err
-	}
+			history, err := client.GetHistory(ctx, scripthash)
+			if err != nil {
+				return false, err
+			}
 
-	if externalAddrCount > 0 {
-		_, err = ws.NewAddressRPCLess(ws.account.AccountNumber, ws.account.KeyScope, externalAddrCount)
-		if err != nil {
-			return err
+			if len(history) > 0 {
+				return true, nil
+			}
 		}
 	}
 
-	if internalAddrCount > 0 {
-		_, err = ws.NewChangeAddressRPCLess(ws.account.AccountNumber, ws.account.KeyScope, internalAddrCount)
-		if err != nil {
-			return err
-		}
-	}
+	return false, nil
+}
 
-	return nil
+// Recover discovers the used addresses of the opened account on both branches,
+// up to the gap limit past the last used one, and restores their history from
+// the Electrum server. The progress callback is called after each address.
+func (ws *WalletService) Recover(progress func(*ScanStats)) (*ScanStats, error) {
+	return ws.RescanHistory(-1, progress)
 }
 
-func (ws *WalletService) backupData(seed []byte) (hexData string, words []string, err error) {
+func (ws *WalletService) backupData(entropy, seed []byte) (hexData string, words []string, err error) {
 
 	// HEX format
 	hexData = hex.EncodeToString(seed)
 
-	mnemonic, err := bip39.NewMnemonic(seed)
+	mnemonic, err := bip39.NewMnemonic(entropy)
 	if err != nil {
-		err = fmt.Errorf("unable to generate mnemonic: seed:%d seedLen:%d %v", len(seed), len(seed), err)
+		err = fmt.Errorf("unable to generate mnemonic: seed:%d seedLen:%d %v", len(entropy), len(seed), err)
 		return
 	}
 	words = strings.Fields(mnemonic)
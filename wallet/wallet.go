@@ -18,6 +18,7 @@ import (
 	"github.com/flokiorg/go-flokicoin/wire"
 	"github.com/flokiorg/walletd/waddrmgr"
 	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
 )
 
 type WalletParams struct {
@@ -28,6 +29,7 @@ type WalletParams struct {
 	AddressScope   waddrmgr.KeyScope
 	ElectrumServer string
 	AccountID      uint32
+	GapLimit       uint32
 }
 
 type WalletAccess struct {
@@ -39,8 +41,7 @@ type WalletAccess struct {
 }
 
 var (
-	ErrWalletNotfound        = errors.New("wallet not found")
-	recoveryWindow    uint32 = 250
+	ErrWalletNotfound = errors.New("wallet not found")
 )
 
 func New(params *WalletParams) *WalletAccess {
@@ -49,25 +50,55 @@ func New(params *WalletParams) *WalletAccess {
 	}
 }
 
-func (wa *WalletAccess) CreateWallet(privPass []byte, seedLen uint8, accountName string) ([]byte, error) {
+func (wa *WalletAccess) Loader() *wallet.Loader {
+	return wa.loader
+}
+
+// PublicPassword returns the passphrase used to decrypt the public data
+// (addresses, history) of the wallet.
+func (wa *WalletAccess) PublicPassword() string {
+	return wa.params.PublicPassword
+}
+
+// SetPublicPassword replaces the passphrase used for the next wallet creation
+// or opening.
+func (wa *WalletAccess) SetPublicPassword(pubPass string) {
+	wa.params.PublicPassword = pubPass
+}
+
+// CreateWallet creates a wallet from fresh entropy and returns the entropy
+// backing the mnemonic along with the seed derived from it. See
+// SeedFromEntropy for the meaning of seedPass.
+func (wa *WalletAccess) CreateWallet(privPass []byte, seedLen uint8, accountName string, seedPass *string) ([]byte, []byte, error) {
+
+	entropy, err := hdkeychain.GenerateSeed(seedLen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate seed: %v", err)
+	}
 
-	seed, err := hdkeychain.GenerateSeed(seedLen)
+	seed, err := SeedFromEntropy(entropy, seedPass)
 	if err != nil {
-		return nil, fmt.Errorf("unable to generate seed: %v", err)
+		return nil, nil, err
 	}
 
-	return seed, wa.createSimpleWallet(seed, privPass, accountName)
+	return entropy, seed, wa.createSimpleWallet(seed, privPass, accountName, time.Now())
 }
 
-func (wa *WalletAccess) RestoreWallet(seed, privPass []byte, accountName string) error {
-	return wa.createSimpleWallet(seed, privPass, accountName)
+// RestoreWallet creates a wallet from an existing seed. The birthday is the
+// earliest time the wallet may have been used, a zero birthday means it may
+// be as old as the chain.
+func (wa *WalletAccess) RestoreWallet(seed, privPass []byte, accountName string, birthday time.Time) error {
+	if birthday.IsZero() {
+		birthday = wa.params.Network.GenesisBlock.Header.Timestamp
+	}
+	return wa.createSimpleWallet(seed, privPass, accountName, birthday)
 }
 
-func (wa *WalletAccess) createSimpleWallet(seed, privPass []byte, accountName string) error {
+func (wa *WalletAccess) createSimpleWallet(seed, privPass []byte, accountName string, birthday time.Time) error {
 
-	wa.loader = wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, recoveryWindow)
+	wa.loader = wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, wa.GapLimit())
 
-	w, err := wa.loader.CreateNewWallet([]byte(wa.params.PublicPassword), privPass, seed, time.Now()) // wa.params.Network.GenesisBlock.Header.Timestamp
+	w, err := wa.loader.CreateNewWallet([]byte(wa.params.PublicPassword), privPass, seed, birthday)
 	if err != nil {
 		return fmt.Errorf("unable to create wallet: %w", err)
 	}
@@ -95,12 +126,12 @@ func (wa *WalletAccess) createSimpleWallet(seed, privPass []byte, accountName st
 	wa.account = account
 	wa.isOpened = true
 
-	return nil
+	return wa.putMeta(networkKey, []byte(wa.params.Network.Name))
 }
 
 func (wa *WalletAccess) WalletExists() (bool, error) {
 
-	loader := wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, recoveryWindow)
+	loader := wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, DefaultGapLimit)
 
 	// Check if the wallet already exists
 	walletExists, err := loader.WalletExists()
@@ -115,12 +146,31 @@ func (wa *WalletAccess) DestroyWallet() {
 	os.Remove(filepath.Join(wa.params.Path, wallet.WalletDBName))
 }
 
+// TrashWallet closes the wallet and moves its database into dir rather than
+// deleting it, so that it can still be recovered. The new path of the
+// database is returned.
+func (wa *WalletAccess) TrashWallet(dir string) (string, error) {
+	if err := wa.CloseWallet(); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	trashed := filepath.Join(dir, wallet.WalletDBName)
+	if err := os.Rename(filepath.Join(wa.params.Path, wallet.WalletDBName), trashed); err != nil {
+		return "", err
+	}
+	return trashed, nil
+}
+
 func (wa *WalletAccess) OpenWallet() error {
 	if exists, _ := wa.WalletExists(); !exists {
 		return ErrWalletNotfound
 	}
 
-	wa.loader = wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, recoveryWindow)
+	wa.loader = wallet.NewLoader(wa.params.Network, wa.params.Path, true, wa.params.Timeout, wa.GapLimit())
 	w, err := wa.loader.OpenExistingWallet([]byte(wa.params.PublicPassword), false)
 	if err != nil {
 		return err
@@ -134,6 +184,11 @@ func (wa *WalletAccess) OpenWallet() error {
 	wa.account = account
 	wa.Wallet = w
 	wa.isOpened = true
+
+	if err := wa.checkNetwork(); err != nil {
+		wa.CloseWallet()
+		return err
+	}
 	return nil
 }
 
@@ -168,6 +223,40 @@ func (wa *WalletAccess) CreateNewAddress() (chainutil.Address, error) {
 	return addr, nil
 }
 
+// CreateNewAddresses derives count receive addresses of the account in one
+// step and returns them with their path and index.
+func (wa *WalletAccess) CreateNewAddresses(count uint32) ([]*AddressDetails, error) {
+	if !wa.isOpened || wa.account == nil {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	manager, err := wa.Manager.FetchScopedKeyManager(wa.params.AddressScope)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []*AddressDetails
+	err = walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		managed, err := manager.NextExternalAddresses(ns, wa.account.AccountNumber, count)
+		if err != nil {
+			return err
+		}
+		for _, ma := range managed {
+			details, err := addressDetails(ns, manager, wa.account.AccountNumber, ma)
+			if err != nil {
+				return err
+			}
+			addrs = append(addrs, details)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
 func (wa *WalletAccess) SimpleTransferFee(address chainutil.Address, amount chainutil.Amount, feePerByte chainutil.Amount) (*chainutil.Amount, error) {
 
 	script, err := txscript.PayToAddrScript(address)
@@ -220,12 +309,58 @@ func (wa *WalletAccess) SimpleTransfer(privPass []byte, address chainutil.Addres
 
 	// Create the transaction
 	tx, err := wa.SendOutputs(
-		outputs,                  // Outputs
-		&wa.params.AddressScope,  // Key scope
-		wa.account.AccountNumber, // Account ID
-		minconf,                  // Minimum confirmations
-		feePerByte*1000,          // Fee rate
-		nil,                      // Coin selection strategy
+		outputs,                      // Outputs
+		&wa.params.AddressScope,      // Key scope
+		wa.account.AccountNumber,     // Account ID
+		minconf,                      // Minimum confirmations
+		feePerByte*1000,              // Fee rate
+		&wallet.RandomCoinSelector{}, // Coin selection strategy
+		time.Now().GoString(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (wa *WalletAccess) BulkSimpleTransfer(privPass []byte, addresses []chainutil.Address, amounts []chainutil.Amount, feePerByte chainutil.Amount) (*wire.MsgTx, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	if err := wa.Unlock(privPass, nil); err != nil {
+		return nil, err
+	}
+	defer wa.Lock()
+
+	outputs := []*wire.TxOut{}
+
+	for i, address := range addresses {
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create a TxOut for the destination address
+		output := &wire.TxOut{
+			Value:    int64(amounts[i].ToUnit(chainutil.AmountLoki)),
+			PkScript: script,
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	minconf := int32(1) // Minimum confirmations required
+
+	// Create the transaction
+	tx, err := wa.SendOutputs(
+		outputs,                      // Outputs
+		&wa.params.AddressScope,      // Key scope
+		wa.account.AccountNumber,     // Account ID
+		minconf,                      // Minimum confirmations
+		feePerByte*1000,              // Fee rate
+		&wallet.RandomCoinSelector{}, // Coin selection strategy &wallet.RandomCoinSelector{}
 		time.Now().GoString(),
 	)
 	if err != nil {
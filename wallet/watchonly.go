@@ -0,0 +1,345 @@
+// Copyright (c) 2024 The Flokicoin developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flokiorg/go-flokicoin/chaincfg"
+	"github.com/flokiorg/go-flokicoin/chainutil"
+	"github.com/flokiorg/go-flokicoin/txscript"
+	"github.com/flokiorg/go-flokicoin/wire"
+	"github.com/flokiorg/walletd/chain/electrum"
+	"github.com/flokiorg/walletd/wallet"
+	"github.com/flokiorg/walletd/walletdb"
+)
+
+// watchOnlyKey is the bucket of the fcli bucket holding the watch-only
+// addresses, keyed by encoded address.
+var watchOnlyKey = []byte("watch-only")
+
+var (
+	ErrAddressWatched = errors.New("the address is already watched")
+	ErrAddressOwned   = errors.New("the address belongs to the wallet")
+)
+
+// WatchedTx is a transaction touching a watch-only address.
+type WatchedTx struct {
+	TxID string
+
+	// Height is the block of the transaction, 0 while unconfirmed.
+	Height int32
+	Time   time.Time
+
+	// Amount is the net effect of the transaction on the address.
+	Amount chainutil.Amount
+}
+
+// WatchedAddress is an address tracked without its key. Its history is kept
+// apart from the wallet transactions, so it never counts in the wallet balance
+// nor funds the transactions the wallet builds.
+type WatchedAddress struct {
+	Address string
+
+	// RescanFrom is the height from which the history is fetched.
+	RescanFrom int32
+
+	// Balance is the confirmed and unconfirmed balance of the address as of
+	// the last refresh.
+	Balance chainutil.Amount
+
+	// History holds the transactions from RescanFrom on, oldest first.
+	History []WatchedTx
+
+	// Refreshed is the time of the last refresh, zero if never fetched.
+	Refreshed time.Time
+}
+
+// AddWatchedAddress starts tracking an address the wallet has no key for. Its
+// history is fetched by RefreshWatchedAddresses.
+func (wa *WalletAccess) AddWatchedAddress(addr chainutil.Address, rescanFrom int32) error {
+	if !wa.isOpened {
+		return wallet.ErrNotLoaded
+	}
+
+	if _, err := wa.AddressInfo(addr); err == nil {
+		return ErrAddressOwned
+	}
+
+	return walletdb.Update(wa.Database(), func(tx walletdb.ReadWriteTx) error {
+		bucket, err := watchOnlyBucket(tx)
+		if err != nil {
+			return err
+		}
+		key := []byte(addr.EncodeAddress())
+		if bucket.Get(key) != nil {
+			return ErrAddressWatched
+		}
+		return putWatchedAddress(bucket, &WatchedAddress{
+			Address:    addr.EncodeAddress(),
+			RescanFrom: rescanFrom,
+		})
+	})
+}
+
+// WatchedAddresses returns the watch-only addresses sorted by address.
+func (wa *WalletAccess) WatchedAddresses() ([]*WatchedAddress, error) {
+	if !wa.isOpened {
+		return nil, wallet.ErrNotLoaded
+	}
+
+	var watched []*WatchedAddress
+	err := walletdb.View(wa.Database(), func(tx walletdb.ReadTx) error {
+		meta := tx.ReadBucket(metaBucketKey)
+		if meta == nil {
+			return nil
+		}
+		bucket := meta.NestedReadBucket(watchOnlyKey)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			wad := &WatchedAddress{}
+			if err := json.Unmarshal(v, wad); err != nil {
+				return fmt.Errorf("invalid watch-only record of %s: %w", k, err)
+			}
+			watched = append(watched, wad)
+			return nil
+		})
+	})
+	sort.Slice(watched, func(i, j int) bool {
+		return watched[i].Address < watched[j].Address
+	})
+	return watched, err
+}
+
+// WatchOnlyBalance returns the sum of the balances of the watch-only addresses
+// as of their last refresh.
+func (wa *WalletAccess) WatchOnlyBalance() (chainutil.Amount, error) {
+	watched, err := wa.WatchedAddresses()
+	if err != nil {
+		return 0, err
+	}
+
+	var balance chainutil.Amount
+	for _, wad := range watched {
+		balance += wad.Balance
+	}
+	return balance, nil
+}
+
+// RefreshWatchedAddresses fetches the balance and history of every watch-only
+// address from the Electrum server.
+func (ws *WalletService) RefreshWatchedAddresses() error {
+	watched, err := ws.WatchedAddresses()
+	if err != nil || len(watched) == 0 {
+		return err
+	}
+
+	fetcher, err := ws.newHistoryFetcher()
+	if err != nil {
+		return err
+	}
+	defer fetcher.close()
+
+	for _, wad := range watched {
+		if err := fetcher.refresh(wad); err != nil {
+			return err
+		}
+	}
+
+	return walletdb.Update(ws.Database(), func(tx walletdb.ReadWriteTx) error {
+		bucket, err := watchOnlyBucket(tx)
+		if err != nil {
+			return err
+		}
+		for _, wad := range watched {
+			if err := putWatchedAddress(bucket, wad); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// historyFetcher looks up the history of addresses the wallet has no key for,
+// caching the transactions and block times shared between them.
+type historyFetcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	client  *electrum.Client
+	network *chaincfg.Params
+	txs     map[string]*wire.MsgTx
+	times   map[int32]time.Time
+}
+
+// newHistoryFetcher connects to the Electrum server of the wallet.
+func (ws *WalletService) newHistoryFetcher() (*historyFetcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := electrum.NewClient(ws.params.ElectrumServer, nil)
+	startCtx, startCancel := context.WithTimeout(ctx, time.Second*10)
+	defer startCancel()
+	if err := client.Start(startCtx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &historyFetcher{
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  client,
+		network: ws.params.Network,
+		txs:     make(map[string]*wire.MsgTx),
+		times:   make(map[int32]time.Time),
+	}, nil
+}
+
+func (f *historyFetcher) close() {
+	f.client.Shutdown()
+	f.cancel()
+}
+
+// fetchTx returns a transaction by id.
+func (f *historyFetcher) fetchTx(txid string) (*wire.MsgTx, error) {
+	if msgTx, ok := f.txs[txid]; ok {
+		return msgTx, nil
+	}
+	raw, err := f.client.GetRawTransaction(f.ctx, txid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tx %s: %v", txid, err)
+	}
+	txBytes, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	msgTx := &wire.MsgTx{}
+	if err := msgTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, fmt.Errorf("unable to decode tx %s: %v", txid, err)
+	}
+	f.txs[txid] = msgTx
+	return msgTx, nil
+}
+
+// blockTime returns the timestamp of the block at height.
+func (f *historyFetcher) blockTime(height int32) (time.Time, error) {
+	if t, ok := f.times[height]; ok {
+		return t, nil
+	}
+	_, header, err := f.client.GetBlockHash(f.ctx, uint32(height))
+	if err != nil {
+		return time.Time{}, err
+	}
+	f.times[height] = header.Timestamp
+	return header.Timestamp, nil
+}
+
+// refresh updates the balance and history of an address.
+func (f *historyFetcher) refresh(wad *WatchedAddress) error {
+	addr, err := chainutil.DecodeAddress(wad.Address, f.network)
+	if err != nil {
+		return err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	scripthash, err := electrum.AddressToElectrumScriptHash(wad.Address, f.network)
+	if err != nil {
+		return err
+	}
+
+	history, err := f.client.GetHistory(f.ctx, scripthash)
+	if err != nil {
+		return fmt.Errorf("unable to fetch history of %s: %v", wad.Address, err)
+	}
+	balance, err := f.client.GetBalance(f.ctx, scripthash)
+	if err != nil {
+		return fmt.Errorf("unable to fetch balance of %s: %v", wad.Address, err)
+	}
+
+	// Outputs of the address can only be spent by transactions of its
+	// history, spending a transaction also in its history.
+	known := make(map[string]bool, len(history))
+	for _, h := range history {
+		known[h.Hash] = true
+	}
+
+	wad.History = wad.History[:0]
+	for _, h := range history {
+		// Unconfirmed transactions are reported with a height of 0 or -1.
+		height := max(h.Height, 0)
+		if height > 0 && height < wad.RescanFrom {
+			continue
+		}
+
+		msgTx, err := f.fetchTx(h.Hash)
+		if err != nil {
+			return err
+		}
+
+		var amount int64
+		for _, out := range msgTx.TxOut {
+			if bytes.Equal(out.PkScript, pkScript) {
+				amount += out.Value
+			}
+		}
+		for _, in := range msgTx.TxIn {
+			prevHash := in.PreviousOutPoint.Hash.String()
+			if !known[prevHash] {
+				continue
+			}
+			prevTx, err := f.fetchTx(prevHash)
+			if err != nil {
+				return err
+			}
+			if idx := in.PreviousOutPoint.Index; idx < uint32(len(prevTx.TxOut)) &&
+				bytes.Equal(prevTx.TxOut[idx].PkScript, pkScript) {
+				amount -= prevTx.TxOut[idx].Value
+			}
+		}
+
+		wtx := WatchedTx{TxID: h.Hash, Height: height, Amount: chainutil.Amount(amount)}
+		if height > 0 {
+			if wtx.Time, err = f.blockTime(height); err != nil {
+				return err
+			}
+		}
+		wad.History = append(wad.History, wtx)
+	}
+	sort.SliceStable(wad.History, func(i, j int) bool {
+		hi, hj := wad.History[i].Height, wad.History[j].Height
+		return hi != 0 && (hj == 0 || hi < hj)
+	})
+
+	wad.Balance = chainutil.Amount(balance.Confirmed + balance.Unconfirmed)
+	wad.Refreshed = time.Now()
+	return nil
+}
+
+// watchOnlyBucket returns the bucket of the watch-only addresses, creating it
+// if needed.
+func watchOnlyBucket(tx walletdb.ReadWriteTx) (walletdb.ReadWriteBucket, error) {
+	meta, err := tx.CreateTopLevelBucket(metaBucketKey)
+	if err != nil {
+		return nil, err
+	}
+	return meta.CreateBucketIfNotExists(watchOnlyKey)
+}
+
+func putWatchedAddress(bucket walletdb.ReadWriteBucket, wad *WatchedAddress) error {
+	value, err := json.Marshal(wad)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(wad.Address), value)
+}